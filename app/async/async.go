@@ -15,7 +15,7 @@ func RunTask(ctx *context.Context, task Task, wg ...*sync.WaitGroup) {
 
 func RunTaskWithName(ctx *context.Context, action string, task Task, wg ...*sync.WaitGroup) {
 	if wg == nil || len(wg) == 0 {
-		go execute(ctx, action, task, "task")
+		go execute(ctx, action, task, false, "task")
 		return
 	}
 
@@ -23,7 +23,7 @@ func RunTaskWithName(ctx *context.Context, action string, task Task, wg ...*sync
 	waitGroup.Add(1)
 	go func(wg *sync.WaitGroup) {
 		defer wg.Done()
-		execute(ctx, action, task, "task")
+		execute(ctx, action, task, false, "task")
 	}(waitGroup)
 }
 
@@ -40,7 +40,8 @@ func RunFuncWithName(ctx *context.Context, action string, process func(ctx conte
 	RunTaskWithName(ctx, action, task, wg...)
 }
 
-func execute(ctx *context.Context, action string, task Task, executeType ...string) {
+// execute runs task with new action log, task inherits deadline and cancellation of caller if inheritDeadline is true
+func execute(ctx *context.Context, action string, task Task, inheritDeadline bool, executeType ...string) {
 	actionType := "executor"
 	if executeType != nil && len(executeType) > 0 {
 		actionType = executeType[0]
@@ -66,7 +67,11 @@ func execute(ctx *context.Context, action string, task Task, executeType ...stri
 		}
 	}()
 
-	innerCtx := context.WithValue(context.Background(), logKey.Id, actionLog.Id)
+	parent := context.Background()
+	if inheritDeadline && ctx != nil {
+		parent = *ctx
+	}
+	innerCtx := context.WithValue(parent, logKey.Id, actionLog.Id)
 	innerCtx = context.WithValue(innerCtx, logKey.Action, actionName)
 	innerCtx = context.WithValue(innerCtx, logKey.Stat, statMap)
 	innerCtx = context.WithValue(innerCtx, logKey.Context, contextMap)
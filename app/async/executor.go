@@ -2,6 +2,8 @@ package async
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/odycenter/std-library/app/web/metric"
 	"github.com/panjf2000/ants/v2"
 	"log"
@@ -11,23 +13,89 @@ import (
 )
 
 type IExecutor interface {
-	Submit(ctx *context.Context, action string, process func(ctx context.Context))
+	Submit(ctx *context.Context, action string, process func(ctx context.Context)) error
+	SubmitTask(ctx *context.Context, action string, task Task) error
 	Close()
 	Running() int
 	Free() int
 	Waiting() int
 }
 
+// RejectionPolicy handles task rejected by executor, task is rejected if pool is nonblocking and no worker is available,
+// or too many tasks are blocked on submit, task submitted to closed executor always returns ErrRejected
+type RejectionPolicy int
+
+const (
+	RejectError      RejectionPolicy = iota // Submit returns ErrRejected, it's default
+	RejectDrop                              // task is dropped, Submit returns nil
+	RejectCallerRuns                        // task runs in caller goroutine, which slows down caller
+)
+
+func (p RejectionPolicy) String() string {
+	switch p {
+	case RejectDrop:
+		return "drop"
+	case RejectCallerRuns:
+		return "caller_runs"
+	default:
+		return "error"
+	}
+}
+
+var ErrRejected = errors.New("task rejected by executor")
+
+type Option func(options *executorOptions)
+
+type executorOptions struct {
+	ants            []ants.Option
+	rejection       RejectionPolicy
+	inheritDeadline bool
+}
+
+// MaxBlockingTasks limits tasks blocked on submit when all workers are busy, 0 means no limit
+func MaxBlockingTasks(n int) Option {
+	return func(options *executorOptions) {
+		options.ants = append(options.ants, ants.WithMaxBlockingTasks(n))
+	}
+}
+
+// Nonblocking rejects task immediately if all workers are busy, instead of blocking caller
+func Nonblocking() Option {
+	return func(options *executorOptions) {
+		options.ants = append(options.ants, ants.WithNonblocking(true))
+	}
+}
+
+func Rejection(policy RejectionPolicy) Option {
+	return func(options *executorOptions) {
+		options.rejection = policy
+	}
+}
+
+// InheritDeadline passes deadline and cancellation of caller context to task, by default task runs with new context,
+// as caller context of http request is canceled once response is sent
+func InheritDeadline() Option {
+	return func(options *executorOptions) {
+		options.inheritDeadline = true
+	}
+}
+
 var executors sync.Map
 var once sync.Once
 
 type Executor struct {
-	Name string
-	Pool *ants.Pool
+	Name    string
+	Pool    *ants.Pool
+	options executorOptions
 }
 
-func New(name string, Size int) IExecutor {
-	pool, err := ants.NewPool(Size)
+// New creates executor with pool size, submit blocks caller if all workers are busy unless Nonblocking or MaxBlockingTasks is set
+func New(name string, Size int, options ...Option) IExecutor {
+	executorOptions := executorOptions{}
+	for _, option := range options {
+		option(&executorOptions)
+	}
+	pool, err := ants.NewPool(Size, executorOptions.ants...)
 	if err != nil {
 		slog.Error("NewExecutor ants.NewPool", "error", err)
 		return nil
@@ -37,8 +105,9 @@ func New(name string, Size int) IExecutor {
 		log.Panic("Executor already exists:", name)
 	}
 	executor := &Executor{
-		Name: name,
-		Pool: pool,
+		Name:    name,
+		Pool:    pool,
+		options: executorOptions,
 	}
 	executors.Store(name, executor)
 	once.Do(func() {
@@ -48,20 +117,39 @@ func New(name string, Size int) IExecutor {
 	return executor
 }
 
-func (e *Executor) SubmitTask(ctx *context.Context, action string, task Task) {
+func (e *Executor) SubmitTask(ctx *context.Context, action string, task Task) error {
 	err := e.Pool.Submit(func() {
-		execute(ctx, action, task)
+		execute(ctx, action, task, e.options.inheritDeadline)
 	})
-	if err != nil {
-		slog.Error("Executor Submit", "error", err)
+	if err == nil {
+		return nil
+	}
+
+	policy := e.options.rejection
+	if errors.Is(err, ants.ErrPoolClosed) {
+		policy = RejectError
+	}
+	metric.ExecutorRejected.WithLabelValues(e.Name, policy.String()).Inc()
+	slog.Warn(fmt.Sprintf("task rejected by executor, executor=%s, action=%s, policy=%s, error=%v", e.Name, action, policy, err))
+	switch policy {
+	case RejectDrop:
+		if task, ok := task.(interface{ rejected(err error) }); ok {
+			task.rejected(err)
+		}
+		return nil
+	case RejectCallerRuns:
+		execute(ctx, action, task, e.options.inheritDeadline)
+		return nil
+	default:
+		return fmt.Errorf("%w, executor=%s, action=%s: %w", ErrRejected, e.Name, action, err)
 	}
 }
 
-func (e *Executor) Submit(ctx *context.Context, action string, process func(ctx context.Context)) {
+func (e *Executor) Submit(ctx *context.Context, action string, process func(ctx context.Context)) error {
 	task := &internalTask{
 		process: process,
 	}
-	e.SubmitTask(ctx, action, task)
+	return e.SubmitTask(ctx, action, task)
 }
 
 func (e *Executor) Close() {
@@ -0,0 +1,158 @@
+package async_test
+
+import (
+	"context"
+	"errors"
+	"github.com/odycenter/std-library/app/async"
+	weberrors "github.com/odycenter/std-library/app/web/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var executors atomic.Int64
+
+// newExecutor names executor after test, executor names are global, so the sequence keeps them unique with -count
+func newExecutor(t *testing.T, size int, options ...async.Option) async.IExecutor {
+	executor := async.New(t.Name()+"-"+strconv.FormatInt(executors.Add(1), 10), size, options...)
+	t.Cleanup(executor.Close)
+	return executor
+}
+
+// busy occupies the only worker of executor until release is closed
+func busy(t *testing.T, executor async.IExecutor) chan struct{} {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	require.NoError(t, executor.Submit(nil, "busy", func(ctx context.Context) {
+		close(started)
+		<-release
+	}))
+	<-started
+	return release
+}
+
+func TestRejectionPolicies(t *testing.T) {
+	executor := newExecutor(t, 1, async.Nonblocking())
+	release := busy(t, executor)
+	err := executor.Submit(nil, "rejected", func(ctx context.Context) {})
+	assert.ErrorIs(t, err, async.ErrRejected)
+	close(release)
+
+	executor = newExecutor(t, 1, async.Nonblocking(), async.Rejection(async.RejectDrop))
+	release = busy(t, executor)
+	var dropped atomic.Bool
+	assert.NoError(t, executor.Submit(nil, "dropped", func(ctx context.Context) { dropped.Store(true) }))
+	close(release)
+	time.Sleep(10 * time.Millisecond)
+	assert.False(t, dropped.Load())
+
+	executor = newExecutor(t, 1, async.Nonblocking(), async.Rejection(async.RejectCallerRuns))
+	release = busy(t, executor)
+	var ran bool
+	assert.NoError(t, executor.Submit(nil, "caller-runs", func(ctx context.Context) { ran = true }))
+	assert.True(t, ran, "task runs in caller goroutine")
+	close(release)
+
+	executor.Close()
+	err = executor.Submit(nil, "closed", func(ctx context.Context) {})
+	assert.ErrorIs(t, err, async.ErrRejected, "closed executor always returns error")
+}
+
+func TestMaxBlockingTasks(t *testing.T) {
+	executor := newExecutor(t, 1, async.MaxBlockingTasks(1))
+	release := busy(t, executor)
+	blocked := make(chan error)
+	go func() {
+		blocked <- executor.Submit(nil, "blocked", func(ctx context.Context) {})
+	}()
+	assert.Eventually(t, func() bool { return executor.Waiting() == 1 }, time.Second, time.Millisecond)
+
+	err := executor.Submit(nil, "rejected", func(ctx context.Context) {})
+	assert.ErrorIs(t, err, async.ErrRejected)
+	close(release)
+	assert.NoError(t, <-blocked)
+}
+
+func TestInheritDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	expected, _ := ctx.Deadline()
+
+	executor := newExecutor(t, 1, async.InheritDeadline())
+	deadline, err := async.Call(executor, &ctx, "deadline", func(ctx context.Context) (time.Time, error) {
+		deadline, _ := ctx.Deadline()
+		return deadline, nil
+	}).Get(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, expected, deadline)
+
+	executor = newExecutor(t, 1)
+	deadline, err = async.Call(executor, &ctx, "deadline", func(ctx context.Context) (time.Time, error) {
+		deadline, _ := ctx.Deadline()
+		return deadline, nil
+	}).Get(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, deadline.IsZero(), "task runs with new context by default")
+
+	canceled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	called := false
+	_, err = async.Call(newExecutor(t, 1, async.InheritDeadline()), &canceled, "canceled", func(ctx context.Context) (int, error) {
+		called = true
+		return 1, nil
+	}).Get(context.Background())
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, called)
+}
+
+func TestCall(t *testing.T) {
+	executor := newExecutor(t, 2)
+
+	value, err := async.Call(executor, nil, "value", func(ctx context.Context) (int, error) {
+		return 42, nil
+	}).Get(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 42, value)
+
+	_, err = async.Call(executor, nil, "error", func(ctx context.Context) (int, error) {
+		return 0, errors.New("failed")
+	}).Get(context.Background())
+	assert.EqualError(t, err, "failed")
+
+	_, err = async.Call(executor, nil, "panic", func(ctx context.Context) (int, error) {
+		weberrors.Internal("failed", "TASK_FAILED")
+		return 0, nil
+	}).Get(context.Background())
+	var code weberrors.Code
+	require.ErrorAs(t, err, &code)
+	assert.Equal(t, "TASK_FAILED", code.ErrorCode())
+
+	future := async.Call(executor, nil, "slow", func(ctx context.Context) (int, error) {
+		time.Sleep(time.Second)
+		return 1, nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = future.Get(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestCallRejected(t *testing.T) {
+	executor := newExecutor(t, 1, async.Nonblocking(), async.Rejection(async.RejectDrop))
+	release := busy(t, executor)
+	defer close(release)
+
+	future := async.Call(executor, nil, "dropped", func(ctx context.Context) (int, error) {
+		return 1, nil
+	})
+	select {
+	case <-future.Done():
+	case <-time.After(time.Second):
+		t.Fatal("future of dropped task is not completed")
+	}
+	_, err := future.Get(context.Background())
+	assert.ErrorIs(t, err, async.ErrRejected)
+}
@@ -0,0 +1,88 @@
+package async
+
+import (
+	"context"
+	"fmt"
+	actionlog "github.com/odycenter/std-library/app/log"
+)
+
+// Future is result of task submitted by Call, it's completed once task returns, panics or is rejected
+type Future[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+func (f *Future[T]) complete(value T, err error) {
+	f.value = value
+	f.err = err
+	close(f.done)
+}
+
+// Done is closed when task is completed
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Get waits until task is completed or ctx is done, panic of task is returned as error
+func (f *Future[T]) Get(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Call submits process to executor and returns future of its result, process is not called if context inherited from caller is already done
+func Call[T any](executor IExecutor, ctx *context.Context, action string, process func(ctx context.Context) (T, error)) *Future[T] {
+	future := &Future[T]{done: make(chan struct{})}
+	task := &futureTask[T]{future: future, process: process}
+	if err := executor.SubmitTask(ctx, action, task); err != nil {
+		var zero T
+		future.complete(zero, err)
+	}
+	return future
+}
+
+type futureTask[T any] struct {
+	future  *Future[T]
+	process func(ctx context.Context) (T, error)
+}
+
+func (t *futureTask[T]) Execute(ctx context.Context) {
+	var value T
+	completed := false
+	defer func() {
+		if !completed {
+			r := recover()
+			t.future.complete(value, panicError(r))
+			if r != nil {
+				panic(r) // action log records panic
+			}
+		}
+	}()
+	err := ctx.Err()
+	if err == nil {
+		value, err = t.process(ctx)
+	}
+	if err != nil {
+		actionlog.Context(&ctx, "error", err.Error())
+	}
+	completed = true
+	t.future.complete(value, err)
+}
+
+// rejected completes future if task is dropped or rejected by executor
+func (t *futureTask[T]) rejected(err error) {
+	var zero T
+	t.future.complete(zero, fmt.Errorf("%w: %w", ErrRejected, err))
+}
+
+func panicError(r any) error {
+	if err, ok := r.(error); ok {
+		return err
+	}
+	return fmt.Errorf("task panic: %v", r)
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"reflect"
+)
+
+const (
+	JSONCodecID        byte = 1
+	MessagePackCodecID byte = 2
+	ProtobufCodecID    byte = 3
+)
+
+// Codec serializes cache values for redis cache store,
+// ID is written into payload header, so existing payload can still be read after codec is changed, custom codec must use ID > 100
+type Codec interface {
+	ID() byte
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+func JSON() Codec {
+	return jsonCodec{}
+}
+
+func MessagePack() Codec {
+	return messagePackCodec{}
+}
+
+// Protobuf only supports cache of protobuf generated message, and can not be used with StaleWhileRevalidate
+func Protobuf() Codec {
+	return protobufCodec{}
+}
+
+type jsonCodec struct{}
+
+func (c jsonCodec) ID() byte {
+	return JSONCodecID
+}
+
+func (c jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (c jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type messagePackCodec struct{}
+
+func (c messagePackCodec) ID() byte {
+	return MessagePackCodecID
+}
+
+func (c messagePackCodec) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (c messagePackCodec) Unmarshal(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+type protobufCodec struct{}
+
+func (c protobufCodec) ID() byte {
+	return ProtobufCodecID
+}
+
+func (c protobufCodec) Marshal(v interface{}) ([]byte, error) {
+	message, err := protoMessage(v)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(message)
+}
+
+func (c protobufCodec) Unmarshal(data []byte, v interface{}) error {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("value must be pointer of proto.Message, but was %T", v)
+	}
+	return proto.Unmarshal(data, message)
+}
+
+// protoMessage accepts both message value and pointer, cache values are usually put by value
+func protoMessage(v interface{}) (proto.Message, error) {
+	if message, ok := v.(proto.Message); ok {
+		return message, nil
+	}
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Struct {
+		ptr := reflect.New(value.Type())
+		ptr.Elem().Set(value)
+		if message, ok := ptr.Interface().(proto.Message); ok {
+			return message, nil
+		}
+	}
+	return nil, fmt.Errorf("value must be proto.Message, but was %T", v)
+}
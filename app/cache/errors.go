@@ -0,0 +1,18 @@
+package cache
+
+import "errors"
+
+// ErrNotFound is returned by Cache.Get if loader reports the value does not exist,
+// loader can return ErrNotFound (or error wraps it, or implements NotFound() bool) to enable negative caching
+var ErrNotFound = errors.New("cache value not found")
+
+func IsNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrNotFound) {
+		return true
+	}
+	var notFound interface{ NotFound() bool }
+	return errors.As(err, &notFound) && notFound.NotFound()
+}
@@ -1,9 +1,24 @@
 package cache
 
+import "time"
+
 type Options struct {
 	MinPoolSize int
 	MaxPoolSize int
 	OnError     func(err error)
+	// LeaseTimeout enables distributed lease on loading, only one instance calls loader for same key,
+	// others wait until value is loaded or lease is timeout, only works with redis cache store
+	LeaseTimeout time.Duration
+	// SoftExpiration enables stale-while-revalidate, value older than SoftExpiration is returned immediately and refreshed in background,
+	// value is reloaded synchronously after hard expiration, must be less than expiration of cache
+	SoftExpiration time.Duration
+	// NotFoundExpiration enables negative caching, if loader returns ErrNotFound, a tombstone is kept for NotFoundExpiration,
+	// Get returns ErrNotFound without calling loader until tombstone expired or evicted
+	NotFoundExpiration time.Duration
+	// Codec serializes values in redis, overrides codec of cache config for this cache, default is JSON
+	Codec Codec
+	// CompressionThreshold enables gzip compression for payload larger than threshold in bytes, 0 means threshold of cache config
+	CompressionThreshold int
 }
 
 type Option func(*Options)
@@ -13,3 +28,33 @@ func OnError(onError func(err error)) Option {
 		o.OnError = onError
 	}
 }
+
+func Lease(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.LeaseTimeout = timeout
+	}
+}
+
+func StaleWhileRevalidate(softExpiration time.Duration) Option {
+	return func(o *Options) {
+		o.SoftExpiration = softExpiration
+	}
+}
+
+func NegativeCache(expiration time.Duration) Option {
+	return func(o *Options) {
+		o.NotFoundExpiration = expiration
+	}
+}
+
+func WithCodec(codec Codec) Option {
+	return func(o *Options) {
+		o.Codec = codec
+	}
+}
+
+func Compression(threshold int) Option {
+	return func(o *Options) {
+		o.CompressionThreshold = threshold
+	}
+}
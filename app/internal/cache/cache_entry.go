@@ -0,0 +1,43 @@
+package internal_cache
+
+import (
+	"reflect"
+	"sync"
+	"time"
+)
+
+// entry types are created per value type, payload is {"loaded_at": <unix millis>, "value": <value>},
+// only used when soft expiration is enabled, to keep payload compatible with existing keys otherwise
+var entryTypes sync.Map // map[reflect.Type]reflect.Type
+
+func entryType(valueType reflect.Type) reflect.Type {
+	if t, ok := entryTypes.Load(valueType); ok {
+		return t.(reflect.Type)
+	}
+	t := reflect.StructOf([]reflect.StructField{
+		{Name: "LoadedAt", Type: reflect.TypeOf(int64(0)), Tag: `json:"loaded_at"`},
+		{Name: "Value", Type: valueType, Tag: `json:"value"`},
+	})
+	entryTypes.Store(valueType, t)
+	return t
+}
+
+func newEntry(value interface{}, loadedAt time.Time) interface{} {
+	v := reflect.Indirect(reflect.ValueOf(value))
+	entry := reflect.New(entryType(v.Type())).Elem()
+	entry.Field(0).SetInt(loadedAt.UnixMilli())
+	entry.Field(1).Set(v)
+	return entry.Interface()
+}
+
+// parseEntry returns pointer of value, entry without loaded_at is treated as invalid, e.g. payload written before soft expiration is enabled
+func parseEntry(entry interface{}) (interface{}, time.Time, bool) {
+	v := reflect.Indirect(reflect.ValueOf(entry))
+	loadedAt := v.Field(0).Int()
+	if loadedAt == 0 {
+		return nil, time.Time{}, false
+	}
+	value := reflect.New(v.Field(1).Type())
+	value.Elem().Set(v.Field(1))
+	return value.Interface(), time.UnixMilli(loadedAt), true
+}
@@ -4,23 +4,38 @@ import (
 	"context"
 	"fmt"
 	"github.com/mohae/deepcopy"
+	"github.com/odycenter/std-library/app/async"
 	"github.com/odycenter/std-library/app/cache"
 	actionlog "github.com/odycenter/std-library/app/log"
+	weberrors "github.com/odycenter/std-library/app/web/errors"
 	reflects "github.com/odycenter/std-library/reflect"
 	"log/slog"
 	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	leaseWaitInterval = 50 * time.Millisecond
+	evictBatchSize    = 500
 )
 
 type CacheImpl struct {
-	name       string
-	typeName   string
-	Expiration time.Duration
-	Options    cache.Options
-	CacheStore CacheStore
+	name              string
+	typeName          string
+	Expiration        time.Duration
+	Options           cache.Options
+	CacheStore        CacheStore
+	Executor          async.IExecutor // to refresh stale values in background, required if soft expiration is enabled
+	loads             singleflight.Group
+	refreshing        sync.Map // map[cacheKey string]bool
+	tombstoneHits     int64
+	tombstonesCreated int64
 }
 
 func (c *CacheImpl) GetTypeName() string {
@@ -51,10 +66,22 @@ func (c *CacheImpl) Get(ctx context.Context, key string, obj interface{}, f func
 	}
 
 	cacheKey := c.cacheKey(key)
-	success := c.CacheStore.Get(ctx, cacheKey, obj)
-	if success {
+	valueType := resultVal.Elem().Type()
+	if c.Options.SoftExpiration <= 0 {
+		if c.CacheStore.Get(ctx, cacheKey, obj) {
+			c.stat(ctx, "cache_hits", 1)
+			return
+		}
+	} else if value, loadedAt, ok := c.getEntry(ctx, cacheKey, valueType); ok {
 		c.stat(ctx, "cache_hits", 1)
-		return
+		if c.stale(loadedAt) {
+			c.stat(ctx, "cache_stale_hits", 1)
+			c.refresh(ctx, key, cacheKey, f, valueType)
+		}
+		return c.copy(value, obj)
+	}
+	if c.tombstoned(ctx, cacheKey) {
+		return cache.ErrNotFound
 	}
 
 	var o interface{}
@@ -64,13 +91,19 @@ func (c *CacheImpl) Get(ctx context.Context, key string, obj interface{}, f func
 		}
 	}()
 
-	slog.DebugContext(ctx, fmt.Sprintf("load value, key=%s", key))
-	o, err = c.load(key, f)
+	value, loaded, _, err := c.loadOnce(ctx, key, cacheKey, f, valueType)
+	if loaded {
+		c.stat(ctx, "cache_misses", 1)
+	} else {
+		c.stat(ctx, "cache_coalesced_hits", 1)
+	}
+	if cache.IsNotFound(err) {
+		return cache.ErrNotFound
+	}
 	if err != nil {
 		return
 	}
-	c.CacheStore.Put(ctx, cacheKey, o, c.Expiration)
-	c.stat(ctx, "cache_misses", 1)
+	o = value
 	return
 }
 
@@ -81,41 +114,271 @@ func (c *CacheImpl) GetByKey(ctx context.Context, key string, obj interface{}) b
 
 func (c *CacheImpl) GetAll(ctx context.Context, keys []string, obj interface{}, f func(key string) (interface{}, error)) (map[string]interface{}, error) {
 	values := make(map[string]interface{}, len(keys))
-	newValues := make(map[string]interface{})
 	cacheKeys := c.cacheKeys(keys...)
-	cacheValues, _ := c.CacheStore.GetAll(ctx, obj, cacheKeys...)
+	valueType := reflect.Indirect(reflect.ValueOf(obj)).Type()
+	cacheValues := c.getAll(ctx, obj, valueType, keys, cacheKeys, f)
 	c.stat(ctx, "cache_hits", float64(len(cacheValues)))
+	tombstones := c.tombstones(ctx, cacheKeys, cacheValues)
+	misses := 0
 	for i, key := range keys {
 		cacheKey := cacheKeys[i]
 		cacheValue := cacheValues[cacheKey]
 		if cacheValue == nil {
-			slog.DebugContext(ctx, fmt.Sprintf("load value, key=%s", key))
-			val, err := c.load(key, f)
+			if tombstones[cacheKey] {
+				continue
+			}
+			value, loaded, shared, err := c.loadOnce(ctx, key, cacheKey, f, valueType)
+			if loaded {
+				misses++
+			} else {
+				c.stat(ctx, "cache_coalesced_hits", 1)
+			}
+			if cache.IsNotFound(err) {
+				continue
+			}
 			if err != nil {
 				return nil, err
 			}
-			cacheValue = val
-			newValues[cacheKey] = cacheValue
+			cacheValue = value
+			if shared {
+				// value is shared with other callers, copy to avoid dirty data
+				cacheValue = deepcopy.Copy(cacheValue)
+			}
 		}
 		if reflect.TypeOf(cacheValue).Kind() == reflect.Ptr {
 			cacheValue = reflect.ValueOf(cacheValue).Elem().Interface()
 		}
 		values[key] = cacheValue
 	}
-	if len(newValues) > 0 {
-		c.CacheStore.PutAll(ctx, newValues, c.Expiration)
-		c.stat(ctx, "cache_misses", float64(len(newValues)))
+	if misses > 0 {
+		c.stat(ctx, "cache_misses", float64(misses))
 	}
 	return values, nil
 }
 
+// getAll returns values by cache key, with soft expiration enabled, stale values are returned and refreshed in background
+func (c *CacheImpl) getAll(ctx context.Context, obj interface{}, valueType reflect.Type, keys, cacheKeys []string, f func(key string) (interface{}, error)) map[string]any {
+	if c.Options.SoftExpiration <= 0 {
+		cacheValues, _ := c.CacheStore.GetAll(ctx, obj, cacheKeys...)
+		return cacheValues
+	}
+
+	entries, _ := c.CacheStore.GetAll(ctx, reflect.New(entryType(valueType)).Elem().Interface(), cacheKeys...)
+	cacheValues := make(map[string]any, len(entries))
+	for i, cacheKey := range cacheKeys {
+		entry, ok := entries[cacheKey]
+		if !ok {
+			continue
+		}
+		value, loadedAt, ok := parseEntry(entry)
+		if !ok {
+			continue
+		}
+		if c.stale(loadedAt) {
+			c.stat(ctx, "cache_stale_hits", 1)
+			c.refresh(ctx, keys[i], cacheKey, f, valueType)
+		}
+		cacheValues[cacheKey] = value
+	}
+	return cacheValues
+}
+
+func (c *CacheImpl) getEntry(ctx context.Context, cacheKey string, valueType reflect.Type) (interface{}, time.Time, bool) {
+	entry := reflect.New(entryType(valueType)).Interface()
+	if !c.CacheStore.Get(ctx, cacheKey, entry) {
+		return nil, time.Time{}, false
+	}
+	return parseEntry(entry)
+}
+
+// getValue returns pointer of value from cache store
+func (c *CacheImpl) getValue(ctx context.Context, cacheKey string, valueType reflect.Type) (interface{}, bool) {
+	if c.Options.SoftExpiration > 0 {
+		value, _, ok := c.getEntry(ctx, cacheKey, valueType)
+		return value, ok
+	}
+	value := reflect.New(valueType).Interface()
+	return value, c.CacheStore.Get(ctx, cacheKey, value)
+}
+
+func (c *CacheImpl) put(ctx context.Context, cacheKey string, value interface{}) bool {
+	if c.Options.SoftExpiration > 0 {
+		value = newEntry(value, time.Now())
+	}
+	return c.CacheStore.Put(ctx, cacheKey, value, c.Expiration)
+}
+
+func (c *CacheImpl) putAll(ctx context.Context, values map[string]any) bool {
+	if c.Options.SoftExpiration > 0 {
+		now := time.Now()
+		entries := make(map[string]any, len(values))
+		for k, v := range values {
+			entries[k] = newEntry(v, now)
+		}
+		values = entries
+	}
+	return c.CacheStore.PutAll(ctx, values, c.Expiration)
+}
+
+func (c *CacheImpl) stale(loadedAt time.Time) bool {
+	return time.Since(loadedAt) >= c.Options.SoftExpiration
+}
+
+// refresh reloads value in background, only one refresh per key is running at same time,
+// refresh failure is recorded in action log of refresh task, caller still gets stale value
+func (c *CacheImpl) refresh(ctx context.Context, key, cacheKey string, f func(key string) (interface{}, error), valueType reflect.Type) {
+	if _, refreshing := c.refreshing.LoadOrStore(cacheKey, true); refreshing {
+		return
+	}
+	err := c.Executor.Submit(&ctx, "cache:refresh", func(ctx context.Context) {
+		defer c.refreshing.Delete(cacheKey)
+		actionlog.Context(&ctx, "cache", c.name)
+		actionlog.Context(&ctx, "key", key)
+		_, _, _, err := c.loadOnce(ctx, key, cacheKey, f, valueType)
+		if cache.IsNotFound(err) {
+			slog.InfoContext(ctx, fmt.Sprintf("value not found on refresh, evict cache, key=%s", cacheKey))
+			c.CacheStore.Delete(ctx, cacheKey)
+			return
+		}
+		if err != nil {
+			weberrors.Internal(fmt.Sprintf("failed to refresh cache, key=%s, error=%v", cacheKey, err), "CACHE_REFRESH_FAILED")
+		}
+	})
+	if err != nil { // rejected, e.g. executor is closed, allow next refresh
+		c.refreshing.Delete(cacheKey)
+	}
+}
+
+// loadOnce coalesces concurrent loading of same key within process, loaded value or tombstone is put into cache store by the caller executing loader,
+// loaded is true only for that caller, shared is true if the value is also returned to other callers,
+// with lease enabled, only the lease holder across instances calls loader, others wait until value is put by holder
+func (c *CacheImpl) loadOnce(ctx context.Context, key, cacheKey string, f func(key string) (interface{}, error), valueType reflect.Type) (value interface{}, loaded bool, shared bool, err error) {
+	value, err, shared = c.loads.Do(cacheKey, func() (interface{}, error) {
+		loaded = true
+		locker, ok := c.CacheStore.(CacheLocker)
+		if c.Options.LeaseTimeout <= 0 || !ok {
+			slog.DebugContext(ctx, fmt.Sprintf("load value, key=%s", key))
+			return c.loadAndStore(ctx, key, cacheKey, f)
+		}
+		return c.loadWithLease(ctx, key, cacheKey, f, locker, valueType)
+	})
+	return
+}
+
+// loadAndStore puts loaded value into cache store, or tombstone if value is not found
+func (c *CacheImpl) loadAndStore(ctx context.Context, key, cacheKey string, f func(key string) (interface{}, error)) (interface{}, error) {
+	val, err := c.load(key, f)
+	if err == nil {
+		c.put(ctx, cacheKey, val)
+	} else if cache.IsNotFound(err) {
+		c.putTombstones(ctx, cacheKey)
+	}
+	return val, err
+}
+
+func (c *CacheImpl) loadWithLease(ctx context.Context, key, cacheKey string, f func(key string) (interface{}, error), locker CacheLocker, valueType reflect.Type) (interface{}, error) {
+	lockKey := "lock:" + cacheKey
+	start := time.Now()
+	for {
+		token, locked := locker.Lock(ctx, lockKey, c.Options.LeaseTimeout)
+		if locked {
+			return c.loadWithinLease(ctx, key, cacheKey, f, locker, lockKey, token)
+		}
+		if time.Since(start) >= c.Options.LeaseTimeout {
+			slog.WarnContext(ctx, fmt.Sprintf("wait lease timeout, load value without lease, key=%s", key))
+			return c.loadAndStore(ctx, key, cacheKey, f)
+		}
+		c.stat(ctx, "cache_lease_waits", 1)
+		time.Sleep(leaseWaitInterval)
+		if value, ok := c.getValue(ctx, cacheKey, valueType); ok {
+			return value, nil
+		}
+		if c.tombstoned(ctx, cacheKey) {
+			return nil, cache.ErrNotFound
+		}
+	}
+}
+
+func (c *CacheImpl) loadWithinLease(ctx context.Context, key, cacheKey string, f func(key string) (interface{}, error), locker CacheLocker, lockKey, token string) (interface{}, error) {
+	slog.DebugContext(ctx, fmt.Sprintf("load value with lease, key=%s", key))
+	val, err := c.loadAndStore(ctx, key, cacheKey, f)
+	// lease may be expired and acquired by others if loading takes too long, token guards against releasing theirs
+	locker.Unlock(ctx, lockKey, token)
+	return val, err
+}
+
+func tombstoneKey(cacheKey string) string {
+	return "tombstone:" + cacheKey
+}
+
+func (c *CacheImpl) tombstoned(ctx context.Context, cacheKey string) bool {
+	if c.Options.NotFoundExpiration <= 0 {
+		return false
+	}
+	var tombstone bool
+	if c.CacheStore.Get(ctx, tombstoneKey(cacheKey), &tombstone) && tombstone {
+		atomic.AddInt64(&c.tombstoneHits, 1)
+		c.stat(ctx, "cache_tombstone_hits", 1)
+		return true
+	}
+	return false
+}
+
+// tombstones returns cache keys which are missing in cacheValues and have tombstone
+func (c *CacheImpl) tombstones(ctx context.Context, cacheKeys []string, cacheValues map[string]any) map[string]bool {
+	if c.Options.NotFoundExpiration <= 0 || len(cacheValues) == len(cacheKeys) {
+		return nil
+	}
+	var keys []string
+	for _, cacheKey := range cacheKeys {
+		if cacheValues[cacheKey] == nil {
+			keys = append(keys, tombstoneKey(cacheKey))
+		}
+	}
+	values, _ := c.CacheStore.GetAll(ctx, false, keys...)
+	result := make(map[string]bool, len(values))
+	for _, cacheKey := range cacheKeys {
+		if values[tombstoneKey(cacheKey)] != nil {
+			result[cacheKey] = true
+		}
+	}
+	if len(result) > 0 {
+		atomic.AddInt64(&c.tombstoneHits, int64(len(result)))
+		c.stat(ctx, "cache_tombstone_hits", float64(len(result)))
+	}
+	return result
+}
+
+func (c *CacheImpl) putTombstones(ctx context.Context, cacheKey ...string) {
+	if c.Options.NotFoundExpiration <= 0 || len(cacheKey) == 0 {
+		return
+	}
+	values := make(map[string]any, len(cacheKey))
+	for _, k := range cacheKey {
+		values[tombstoneKey(k)] = true
+	}
+	if c.CacheStore.PutAll(ctx, values, c.Options.NotFoundExpiration) {
+		atomic.AddInt64(&c.tombstonesCreated, int64(len(cacheKey)))
+	}
+}
+
+// TombstoneHits returns count of lookups answered by tombstone since start
+func (c *CacheImpl) TombstoneHits() int64 {
+	return atomic.LoadInt64(&c.tombstoneHits)
+}
+
+// TombstonesCreated returns count of tombstones put by this instance since start
+func (c *CacheImpl) TombstonesCreated() int64 {
+	return atomic.LoadInt64(&c.tombstonesCreated)
+}
+
 func (c *CacheImpl) load(key string, f func(key string) (interface{}, error)) (interface{}, error) {
 	val, err := f(key)
 	if err != nil {
 		return nil, err
 	}
 	if val == nil {
-		return nil, fmt.Errorf("value must not be null, key=%s", key)
+		return nil, fmt.Errorf("value must not be null, return cache.ErrNotFound if value does not exist, key=%s", key)
 	}
 
 	err = c.checkLoaderReturnType(val)
@@ -135,7 +398,7 @@ func (c *CacheImpl) Put(ctx context.Context, key string, obj interface{}) bool {
 
 	cacheKey := c.cacheKey(key)
 
-	return c.CacheStore.Put(ctx, cacheKey, obj, c.Expiration)
+	return c.put(ctx, cacheKey, obj)
 }
 
 func (c *CacheImpl) PutAll(ctx context.Context, values map[string]any) bool {
@@ -148,18 +411,89 @@ func (c *CacheImpl) PutAll(ctx context.Context, values map[string]any) bool {
 		}
 		cacheValues[c.cacheKey(key)] = value
 	}
-	return c.CacheStore.PutAll(ctx, cacheValues, c.Expiration)
+	return c.putAll(ctx, cacheValues)
 }
 
 func (c *CacheImpl) Evict(ctx context.Context, key string) bool {
 	cacheKey := c.cacheKey(key)
-	return c.CacheStore.Delete(ctx, cacheKey)
+	return c.CacheStore.Delete(ctx, cacheKey, tombstoneKey(cacheKey))
 }
 func (c *CacheImpl) EvictAll(ctx context.Context, key ...string) bool {
 	cacheKeys := c.cacheKeys(key...)
+	for _, cacheKey := range c.cacheKeys(key...) {
+		cacheKeys = append(cacheKeys, tombstoneKey(cacheKey))
+	}
 	return c.CacheStore.Delete(ctx, cacheKeys...)
 }
 
+// Keys lists keys of this cache by glob pattern, returned keys are without cache name prefix
+func (c *CacheImpl) Keys(ctx context.Context, pattern string, cursor uint64, count int64) ([]KeyInfo, uint64, error) {
+	scanner, ok := c.CacheStore.(KeyScanner)
+	if !ok {
+		return nil, 0, fmt.Errorf("cache store does not support key browsing, name=%s", c.name)
+	}
+	if pattern == "" {
+		pattern = "*"
+	}
+	keys, next, err := scanner.Scan(ctx, c.cacheKey(pattern), cursor, count)
+	if err != nil {
+		return nil, 0, err
+	}
+	infos := make([]KeyInfo, 0, len(keys))
+	for _, cacheKey := range keys {
+		info, ok := scanner.Inspect(ctx, cacheKey)
+		if !ok {
+			continue // expired or evicted during scan
+		}
+		info.Key = strings.TrimPrefix(cacheKey, c.name+":")
+		infos = append(infos, info)
+	}
+	return infos, next, nil
+}
+
+// KeyInfo returns ttl and payload size of key
+func (c *CacheImpl) KeyInfo(ctx context.Context, key string) (KeyInfo, bool) {
+	scanner, ok := c.CacheStore.(KeyScanner)
+	if !ok {
+		return KeyInfo{}, false
+	}
+	info, ok := scanner.Inspect(ctx, c.cacheKey(key))
+	info.Key = key
+	return info, ok
+}
+
+// EvictNamespace evicts all keys and tombstones of this cache, returns number of evicted keys
+func (c *CacheImpl) EvictNamespace(ctx context.Context) (int, error) {
+	scanner, ok := c.CacheStore.(KeyScanner)
+	if !ok {
+		return 0, fmt.Errorf("cache store does not support key browsing, name=%s", c.name)
+	}
+	evicted := 0
+	for _, pattern := range []string{c.cacheKey("*"), tombstoneKey(c.cacheKey("*"))} {
+		var cursor uint64
+		for {
+			keys, next, err := scanner.Scan(ctx, pattern, cursor, evictBatchSize)
+			if err != nil {
+				return evicted, err
+			}
+			if len(keys) > 0 {
+				c.CacheStore.Delete(ctx, keys...)
+				evicted += len(keys)
+			}
+			if next == 0 {
+				break
+			}
+			// local store cursor is offset of remaining keys, which are shifted after deletion
+			if _, local := c.CacheStore.(*LocalCacheStore); local {
+				next = 0
+			}
+			cursor = next
+		}
+	}
+	slog.InfoContext(ctx, fmt.Sprintf("evict cache namespace, name=%s, evicted=%d", c.name, evicted))
+	return evicted, nil
+}
+
 func (c *CacheImpl) checkType(obj interface{}) error {
 	if c.typeName != reflects.StructFullName(obj) {
 		return fmt.Errorf("illegal usage, cache type is not correct, must be %s, please check", c.typeName)
@@ -208,9 +542,5 @@ func (c *CacheImpl) cacheKeys(key ...string) []string {
 }
 
 func (c *CacheImpl) stat(ctx context.Context, key string, value float64) {
-	val := actionlog.GetStat(&ctx, key)
-	if val != 0 {
-		value += val
-	}
-	actionlog.Stat(&ctx, key, value)
+	stat(ctx, key, value)
 }
@@ -0,0 +1,226 @@
+package internal_cache
+
+import (
+	"context"
+	"fmt"
+	"github.com/odycenter/std-library/app/async"
+	"github.com/odycenter/std-library/app/cache"
+	reflects "github.com/odycenter/std-library/reflect"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type cacheImplTest struct {
+	Name string
+}
+
+func newCacheImpl(store CacheStore, options cache.Options) *CacheImpl {
+	impl := &CacheImpl{Expiration: time.Hour, CacheStore: store, Options: options}
+	impl.TypeName(reflects.StructFullName(cacheImplTest{}))
+	return impl
+}
+
+func slowLoader(calls *int32) func(key string) (interface{}, error) {
+	return func(key string) (interface{}, error) {
+		atomic.AddInt32(calls, 1)
+		time.Sleep(100 * time.Millisecond)
+		return cacheImplTest{Name: key}, nil
+	}
+}
+
+func TestGetCoalescesLoading(t *testing.T) {
+	store := NewLocalCacheStore()
+	c := newCacheImpl(store, cache.Options{})
+	var calls int32
+	loader := slowLoader(&calls)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				var result cacheImplTest
+				assert.NoError(t, c.Get(context.Background(), "key", &result, loader))
+				assert.Equal(t, "key", result.Name)
+				return
+			}
+			values, err := c.GetAll(context.Background(), []string{"key"}, cacheImplTest{}, loader)
+			assert.NoError(t, err)
+			assert.Equal(t, cacheImplTest{Name: "key"}, values["key"])
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Equal(t, 1, store.Size(), "loaded value is put by the caller executing loader")
+
+	var result cacheImplTest
+	assert.NoError(t, c.Get(context.Background(), "key", &result, loader))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestGetWithLease(t *testing.T) {
+	redis := &fakeRedis{values: make(map[string]string)}
+	var calls int32
+	loader := slowLoader(&calls)
+
+	// each instance has its own CacheImpl, so only lease prevents loading twice
+	wg := sync.WaitGroup{}
+	for i := 0; i < 3; i++ {
+		store := &RedisCacheStore{}
+		store.Initialize(redis)
+		c := newCacheImpl(store, cache.Options{LeaseTimeout: time.Second})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var result cacheImplTest
+			assert.NoError(t, c.Get(context.Background(), "key", &result, loader))
+			assert.Equal(t, "key", result.Name)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	_, locked := redis.values["lock:"+newCacheImpl(nil, cache.Options{}).cacheKey("key")]
+	assert.False(t, locked)
+}
+
+func TestUnlockExpiredLease(t *testing.T) {
+	ctx := context.Background()
+	store := &RedisCacheStore{}
+	store.Initialize(&fakeRedis{values: make(map[string]string)})
+	token, ok := store.Lock(ctx, "lock:key", time.Second)
+	assert.True(t, ok)
+	_, ok = store.Lock(ctx, "lock:key", time.Second)
+	assert.False(t, ok)
+
+	store.Delete(ctx, "lock:key") // expired, then acquired by another instance
+	_, ok = store.Lock(ctx, "lock:key", time.Second)
+	assert.True(t, ok)
+	store.Unlock(ctx, "lock:key", token)
+	_, ok = store.Lock(ctx, "lock:key", time.Second)
+	assert.False(t, ok, "lease held by others must not be released")
+}
+
+func TestGetStaleWhileRevalidate(t *testing.T) {
+	c := newCacheImpl(NewLocalCacheStore(), cache.Options{SoftExpiration: 50 * time.Millisecond})
+	c.Executor = async.New("test-cache-refresh", 2)
+	var calls int32
+	var failed atomic.Bool
+	loader := func(key string) (interface{}, error) {
+		if failed.Load() {
+			return nil, fmt.Errorf("loader failed")
+		}
+		n := atomic.AddInt32(&calls, 1)
+		return cacheImplTest{Name: fmt.Sprintf("%s-%d", key, n)}, nil
+	}
+
+	var result cacheImplTest
+	assert.NoError(t, c.Get(context.Background(), "key", &result, loader))
+	assert.Equal(t, "key-1", result.Name)
+
+	time.Sleep(60 * time.Millisecond)
+	assert.NoError(t, c.Get(context.Background(), "key", &result, loader))
+	assert.Equal(t, "key-1", result.Name, "stale value must be returned immediately")
+	assert.Eventually(t, func() bool {
+		values, err := c.GetAll(context.Background(), []string{"key"}, cacheImplTest{}, loader)
+		return err == nil && values["key"] == cacheImplTest{Name: "key-2"}
+	}, time.Second, 10*time.Millisecond)
+
+	failed.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	assert.NoError(t, c.Get(context.Background(), "key", &result, loader))
+	assert.Equal(t, "key-2", result.Name)
+}
+
+func TestGetNegativeCache(t *testing.T) {
+	c := newCacheImpl(NewLocalCacheStore(), cache.Options{NotFoundExpiration: time.Minute})
+	var calls int32
+	loader := func(key string) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		if key == "missing" {
+			return nil, fmt.Errorf("load %s: %w", key, cache.ErrNotFound)
+		}
+		return cacheImplTest{Name: key}, nil
+	}
+
+	var result cacheImplTest
+	for i := 0; i < 3; i++ {
+		assert.ErrorIs(t, c.Get(context.Background(), "missing", &result, loader), cache.ErrNotFound)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Equal(t, int64(1), c.TombstonesCreated())
+	assert.Equal(t, int64(2), c.TombstoneHits())
+
+	values, err := c.GetAll(context.Background(), []string{"found", "missing", "missing2"}, cacheImplTest{}, func(key string) (interface{}, error) {
+		if key == "missing2" {
+			return nil, notFoundError{}
+		}
+		return loader(key)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"found": cacheImplTest{Name: "found"}}, values)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Equal(t, int64(2), c.TombstonesCreated())
+
+	c.Evict(context.Background(), "missing")
+	assert.ErrorIs(t, c.Get(context.Background(), "missing", &result, loader), cache.ErrNotFound)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+type notFoundError struct{}
+
+func (e notFoundError) Error() string {
+	return "not found"
+}
+
+func (e notFoundError) NotFound() bool {
+	return true
+}
+
+func TestKeysAndEvictNamespace(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocalCacheStore()
+	c := newCacheImpl(store, cache.Options{NotFoundExpiration: time.Minute})
+	c.PutAll(ctx, map[string]any{"key1": cacheImplTest{Name: "1"}, "key2": cacheImplTest{Name: "2"}})
+	c.putTombstones(ctx, c.cacheKey("missing"))
+	store.Put(ctx, "other:key1", cacheImplTest{}, time.Hour)
+
+	keys, cursor, err := c.Keys(ctx, "key*", 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), cursor)
+	assert.Len(t, keys, 2)
+	assert.Equal(t, "key1", keys[0].Key)
+	assert.Equal(t, int64(len(`{"Name":"1"}`)), keys[0].Size)
+
+	evicted, err := c.EvictNamespace(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, evicted)
+	assert.Equal(t, 1, store.Size())
+}
+
+func TestPerCacheCodec(t *testing.T) {
+	ctx := context.Background()
+	redis := &fakeRedis{values: make(map[string]string)}
+	store := &RedisCacheStore{}
+	store.Initialize(redis)
+	store.Codec(nil, 0)
+	c := newCacheImpl(store.WithCodec(cache.MessagePack(), 10), cache.Options{})
+
+	value := cacheImplTest{Name: "name of value longer than compression threshold"}
+	c.Put(ctx, "key", value)
+	payload := redis.values[c.cacheKey("key")]
+	assert.Equal(t, []byte{payloadMagic, payloadVersion, cache.MessagePackCodecID, flagGzip}, []byte(payload[:payloadHeaderSize]))
+
+	var result cacheImplTest
+	assert.NoError(t, c.Get(ctx, "key", &result, func(key string) (interface{}, error) {
+		return nil, fmt.Errorf("value must be loaded from redis, key=%s", key)
+	}))
+	assert.Equal(t, value, result)
+
+	other := newCacheImpl(store, cache.Options{})
+	other.Put(ctx, "key", value)
+	assert.Equal(t, byte('{'), redis.values[other.cacheKey("key")][0], "store of other cache still uses json")
+}
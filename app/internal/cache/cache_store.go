@@ -12,3 +12,23 @@ type CacheStore interface {
 	PutAll(ctx context.Context, values map[string]any, expiration time.Duration) bool
 	Delete(ctx context.Context, key ...string) bool
 }
+
+// CacheLocker is implemented by cache store which supports distributed lease,
+// Lock returns owner token of acquired lease, Unlock only releases the lease if it is still held by token
+type CacheLocker interface {
+	Lock(ctx context.Context, key string, expiration time.Duration) (string, bool)
+	Unlock(ctx context.Context, key string, token string)
+}
+
+// KeyScanner is implemented by cache store which supports key browsing, used by /_sys/cache
+type KeyScanner interface {
+	// Scan returns keys match glob pattern, next cursor is 0 if iteration is completed
+	Scan(ctx context.Context, pattern string, cursor uint64, count int64) ([]string, uint64, error)
+	Inspect(ctx context.Context, key string) (KeyInfo, bool)
+}
+
+type KeyInfo struct {
+	Key  string
+	TTL  time.Duration
+	Size int64
+}
@@ -1,34 +1,246 @@
 package internal_cache
 
 import (
+	"container/list"
 	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/mohae/deepcopy"
+	"log/slog"
+	"reflect"
+	"sort"
+	"sync"
 	"time"
 )
 
+const defaultLocalCacheMaxSize = 10000
+
 type LocalCacheStore struct {
 	MaxSize int
+	items   map[string]*list.Element
+	lru     *list.List // front is the most recently used
+	mu      sync.Mutex
+}
+
+type localCacheItem struct {
+	key            string
+	value          interface{}
+	expirationTime time.Time
+}
+
+func (i *localCacheItem) expired(now time.Time) bool {
+	return !now.Before(i.expirationTime)
+}
+
+func NewLocalCacheStore() *LocalCacheStore {
+	return &LocalCacheStore{
+		MaxSize: defaultLocalCacheMaxSize,
+		items:   make(map[string]*list.Element),
+		lru:     list.New(),
+	}
 }
 
 func (c *LocalCacheStore) Get(ctx context.Context, key string, result interface{}) bool {
-	return false // TODO
+	value, ok := c.get(key, time.Now())
+	if !ok {
+		slog.DebugContext(ctx, fmt.Sprintf("[Cache][Get] Key not found:<%s>", key))
+		return false
+	}
+
+	err := c.assign(value, result)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[Cache][Get] key:<%s> failed:%v", key, err))
+		return false
+	}
+	return true
 }
 
 func (c *LocalCacheStore) GetAll(ctx context.Context, obj interface{}, key ...string) (map[string]any, bool) {
-	return nil, false // TODO
+	now := time.Now()
+	result := make(map[string]any, len(key))
+	for _, k := range key {
+		value, ok := c.get(k, now)
+		if !ok {
+			continue
+		}
+		result[k] = deepcopy.Copy(value)
+	}
+	return result, true
 }
 
 func (c *LocalCacheStore) Put(ctx context.Context, key string, obj interface{}, expiration time.Duration) bool {
-	return false // TODO
+	if obj == nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[Cache][Put] value must not be null, key: %s", key))
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.put(key, obj, time.Now().Add(expiration))
+	return true
 }
 
 func (c *LocalCacheStore) PutAll(ctx context.Context, values map[string]any, expiration time.Duration) bool {
-	return false // TODO
+	for k, v := range values {
+		if v == nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("[Cache][PutAll] value must not be null, key: %s", k))
+			return false
+		}
+	}
+	expirationTime := time.Now().Add(expiration)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, v := range values {
+		c.put(k, v, expirationTime)
+	}
+	return true
 }
 
 func (c *LocalCacheStore) Delete(ctx context.Context, key ...string) bool {
-	return false // TODO
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, k := range key {
+		if el, ok := c.items[k]; ok {
+			c.remove(el)
+		}
+	}
+	return true
 }
 
+// Cleanup evicts all expired entries, expired entries are also evicted lazily on Get
 func (c *LocalCacheStore) Cleanup() {
-	// TODO
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	size := c.lru.Len()
+	for el := c.lru.Back(); el != nil; {
+		prev := el.Prev()
+		if el.Value.(*localCacheItem).expired(now) {
+			c.remove(el)
+		}
+		el = prev
+	}
+	slog.Debug(fmt.Sprintf("cleanup local cache store, evicted=%d, size=%d", size-c.lru.Len(), c.lru.Len()))
+}
+
+// Scan iterates keys in sorted order, cursor is offset of matched keys
+func (c *LocalCacheStore) Scan(ctx context.Context, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	matcher, err := globRegexp(pattern)
+	if err != nil {
+		return nil, 0, err
+	}
+	now := time.Now()
+	c.mu.Lock()
+	keys := make([]string, 0, len(c.items))
+	for key, el := range c.items {
+		if !el.Value.(*localCacheItem).expired(now) && matcher.MatchString(key) {
+			keys = append(keys, key)
+		}
+	}
+	c.mu.Unlock()
+
+	sort.Strings(keys)
+	if cursor >= uint64(len(keys)) {
+		return nil, 0, nil
+	}
+	end := cursor + uint64(count)
+	if count <= 0 || end >= uint64(len(keys)) {
+		return keys[cursor:], 0, nil
+	}
+	return keys[cursor:end], end, nil
+}
+
+func (c *LocalCacheStore) Inspect(ctx context.Context, key string) (KeyInfo, bool) {
+	now := time.Now()
+	c.mu.Lock()
+	el, ok := c.items[key]
+	if !ok || el.Value.(*localCacheItem).expired(now) {
+		c.mu.Unlock()
+		return KeyInfo{}, false
+	}
+	item := el.Value.(*localCacheItem)
+	value, ttl := item.value, item.expirationTime.Sub(now)
+	c.mu.Unlock()
+
+	// local value is not serialized, use json size as approximation
+	bs, _ := json.Marshal(value)
+	return KeyInfo{Key: key, TTL: ttl, Size: int64(len(bs))}, true
+}
+
+func (c *LocalCacheStore) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+func (c *LocalCacheStore) get(key string, now time.Time) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	item := el.Value.(*localCacheItem)
+	if item.expired(now) {
+		c.remove(el)
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return item.value, true
+}
+
+// put must be called with lock held, value is copied to avoid being modified by caller after put
+func (c *LocalCacheStore) put(key string, obj interface{}, expirationTime time.Time) {
+	value := reflect.Indirect(reflect.ValueOf(deepcopy.Copy(obj))).Interface()
+	if el, ok := c.items[key]; ok {
+		item := el.Value.(*localCacheItem)
+		item.value = value
+		item.expirationTime = expirationTime
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.lru.PushFront(&localCacheItem{key: key, value: value, expirationTime: expirationTime})
+	c.evict()
+}
+
+// evict removes expired entries first, then least recently used entries until size is within MaxSize
+func (c *LocalCacheStore) evict() {
+	if c.MaxSize <= 0 || c.lru.Len() <= c.MaxSize {
+		return
+	}
+	now := time.Now()
+	for el := c.lru.Back(); el != nil && c.lru.Len() > c.MaxSize; {
+		prev := el.Prev()
+		if el.Value.(*localCacheItem).expired(now) {
+			c.remove(el)
+		}
+		el = prev
+	}
+	for c.lru.Len() > c.MaxSize {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *LocalCacheStore) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.items, el.Value.(*localCacheItem).key)
+}
+
+// assign copies value into result pointer, falls back to json conversion if types are different, e.g. map for /_sys/cache
+func (c *LocalCacheStore) assign(value interface{}, result interface{}) error {
+	resultVal := reflect.ValueOf(result)
+	if resultVal.Kind() != reflect.Ptr || resultVal.IsNil() {
+		return fmt.Errorf("result must be a non-nil pointer, but was %T", result)
+	}
+	target := resultVal.Elem()
+	copied := reflect.ValueOf(deepcopy.Copy(value))
+	if copied.Type().AssignableTo(target.Type()) {
+		target.Set(copied)
+		return nil
+	}
+
+	bs, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bs, result)
 }
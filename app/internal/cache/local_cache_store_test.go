@@ -0,0 +1,113 @@
+package internal_cache_test
+
+import (
+	"context"
+	internalcache "github.com/odycenter/std-library/app/internal/cache"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type localCacheTest struct {
+	Name  string
+	Items []string
+}
+
+func TestLocalCacheStoreGetPut(t *testing.T) {
+	ctx := context.Background()
+	store := internalcache.NewLocalCacheStore()
+	value := localCacheTest{Name: "name", Items: []string{"a"}}
+	assert.True(t, store.Put(ctx, "key", value, time.Minute))
+
+	value.Items[0] = "changed"
+	var result localCacheTest
+	assert.True(t, store.Get(ctx, "key", &result))
+	assert.Equal(t, "name", result.Name)
+	assert.Equal(t, []string{"a"}, result.Items)
+
+	result.Items[0] = "changed"
+	var result2 localCacheTest
+	assert.True(t, store.Get(ctx, "key", &result2))
+	assert.Equal(t, []string{"a"}, result2.Items)
+
+	var view map[string]interface{}
+	assert.True(t, store.Get(ctx, "key", &view))
+	assert.Equal(t, "name", view["Name"])
+
+	assert.True(t, store.Delete(ctx, "key"))
+	assert.False(t, store.Get(ctx, "key", &result))
+}
+
+func TestLocalCacheStoreGetAllPutAll(t *testing.T) {
+	ctx := context.Background()
+	store := internalcache.NewLocalCacheStore()
+	assert.True(t, store.PutAll(ctx, map[string]any{
+		"key1": localCacheTest{Name: "1"},
+		"key2": &localCacheTest{Name: "2"},
+	}, time.Minute))
+
+	values, ok := store.GetAll(ctx, localCacheTest{}, "key1", "key2", "key3")
+	assert.True(t, ok)
+	assert.Len(t, values, 2)
+	assert.Equal(t, localCacheTest{Name: "1"}, values["key1"])
+	assert.Equal(t, localCacheTest{Name: "2"}, values["key2"])
+}
+
+func TestLocalCacheStoreExpiration(t *testing.T) {
+	ctx := context.Background()
+	store := internalcache.NewLocalCacheStore()
+	store.Put(ctx, "expired", localCacheTest{Name: "expired"}, -time.Second)
+	store.Put(ctx, "valid", localCacheTest{Name: "valid"}, time.Minute)
+	assert.Equal(t, 2, store.Size())
+
+	var result localCacheTest
+	assert.False(t, store.Get(ctx, "expired", &result))
+	assert.Equal(t, 1, store.Size())
+
+	store.Put(ctx, "expired", localCacheTest{Name: "expired"}, -time.Second)
+	store.Cleanup()
+	assert.Equal(t, 1, store.Size())
+	assert.True(t, store.Get(ctx, "valid", &result))
+}
+
+func TestLocalCacheStoreMaxSize(t *testing.T) {
+	ctx := context.Background()
+	store := internalcache.NewLocalCacheStore()
+	store.MaxSize = 2
+	store.Put(ctx, "key1", localCacheTest{Name: "1"}, time.Minute)
+	store.Put(ctx, "key2", localCacheTest{Name: "2"}, time.Minute)
+
+	var result localCacheTest
+	assert.True(t, store.Get(ctx, "key1", &result)) // key2 becomes least recently used
+	store.Put(ctx, "key3", localCacheTest{Name: "3"}, time.Minute)
+
+	assert.Equal(t, 2, store.Size())
+	assert.False(t, store.Get(ctx, "key2", &result))
+	assert.True(t, store.Get(ctx, "key1", &result))
+	assert.True(t, store.Get(ctx, "key3", &result))
+}
+
+func TestLocalCacheStoreScan(t *testing.T) {
+	store := internalcache.NewLocalCacheStore()
+	ctx := context.Background()
+	store.PutAll(ctx, map[string]any{"a:1": 1, "a:2": 2, "a:3": 3, "b:1": 1}, time.Hour)
+	store.Put(ctx, "a:expired", 1, -time.Second)
+
+	keys, cursor, err := store.Scan(ctx, "a:*", 0, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a:1", "a:2"}, keys)
+	assert.Equal(t, uint64(2), cursor)
+
+	keys, cursor, err = store.Scan(ctx, "a:*", cursor, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a:3"}, keys)
+	assert.Equal(t, uint64(0), cursor)
+
+	info, ok := store.Inspect(ctx, "b:1")
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), info.Size)
+	assert.InDelta(t, time.Hour, info.TTL, float64(time.Second))
+
+	_, ok = store.Inspect(ctx, "a:expired")
+	assert.False(t, ok)
+}
@@ -0,0 +1,88 @@
+package internal_cache
+
+import (
+	"fmt"
+	"github.com/odycenter/std-library/app/cache"
+	"github.com/odycenter/std-library/compression/gzip"
+	"sync"
+)
+
+// payload header is [magic, version, codec id, flags], followed by encoded body,
+// payload without header is legacy json, plain json payload is still written without header to be readable by previous versions
+const (
+	payloadMagic      byte = 0x00
+	payloadVersion    byte = 1
+	payloadHeaderSize      = 4
+	flagGzip          byte = 1 << 0
+)
+
+var codecs sync.Map // map[byte]cache.Codec
+
+func init() {
+	RegisterCodec(cache.JSON())
+	RegisterCodec(cache.MessagePack())
+	RegisterCodec(cache.Protobuf())
+}
+
+// RegisterCodec makes codec available for decoding payload
+func RegisterCodec(codec cache.Codec) {
+	codecs.Store(codec.ID(), codec)
+}
+
+type payloadCodec struct {
+	codec                cache.Codec
+	compressionThreshold int
+}
+
+func (p *payloadCodec) encode(v interface{}) ([]byte, error) {
+	codec := p.codec
+	if codec == nil {
+		codec = cache.JSON()
+	}
+	body, err := codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var flags byte
+	if p.compressionThreshold > 0 && len(body) > p.compressionThreshold {
+		body, err = gzip.Compress(body)
+		if err != nil {
+			return nil, err
+		}
+		flags |= flagGzip
+	}
+	if codec.ID() == cache.JSONCodecID && flags == 0 {
+		return body, nil
+	}
+
+	payload := make([]byte, 0, payloadHeaderSize+len(body))
+	payload = append(payload, payloadMagic, payloadVersion, codec.ID(), flags)
+	return append(payload, body...), nil
+}
+
+func (p *payloadCodec) decode(payload []byte, v interface{}) error {
+	if len(payload) == 0 || payload[0] != payloadMagic {
+		return cache.JSON().Unmarshal(payload, v)
+	}
+	if len(payload) < payloadHeaderSize {
+		return fmt.Errorf("invalid payload, length=%d", len(payload))
+	}
+	if payload[1] != payloadVersion {
+		return fmt.Errorf("unsupported payload version, version=%d", payload[1])
+	}
+	codec, ok := codecs.Load(payload[2])
+	if !ok {
+		return fmt.Errorf("unknown codec, id=%d", payload[2])
+	}
+
+	body := payload[payloadHeaderSize:]
+	if payload[3]&flagGzip != 0 {
+		var err error
+		body, err = gzip.Decompress(body)
+		if err != nil {
+			return err
+		}
+	}
+	return codec.(cache.Codec).Unmarshal(body, v)
+}
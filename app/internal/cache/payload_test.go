@@ -0,0 +1,68 @@
+package internal_cache
+
+import (
+	"github.com/odycenter/std-library/app/cache"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"strings"
+	"testing"
+)
+
+type payloadTest struct {
+	Name  string
+	Items []string
+}
+
+func TestPayloadJSONCompatible(t *testing.T) {
+	codec := payloadCodec{}
+	payload, err := codec.encode(payloadTest{Name: "name"})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Name":"name","Items":null}`, string(payload))
+
+	var result payloadTest
+	assert.NoError(t, codec.decode(payload, &result))
+	assert.Equal(t, "name", result.Name)
+}
+
+func TestPayloadCodecChanged(t *testing.T) {
+	value := payloadTest{Name: strings.Repeat("name", 100), Items: []string{"a", "b"}}
+	writer := payloadCodec{codec: cache.MessagePack(), compressionThreshold: 100}
+	payload, err := writer.encode(value)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{payloadMagic, payloadVersion, cache.MessagePackCodecID, flagGzip}, payload[:payloadHeaderSize])
+
+	// reader configured with default codec, e.g. previous version during rolling deploy
+	reader := payloadCodec{}
+	var result payloadTest
+	assert.NoError(t, reader.decode(payload, &result))
+	assert.Equal(t, value, result)
+
+	compressed := payloadCodec{compressionThreshold: 100}
+	payload, err = compressed.encode(value)
+	assert.NoError(t, err)
+	assert.Equal(t, cache.JSONCodecID, payload[2])
+	result = payloadTest{}
+	assert.NoError(t, reader.decode(payload, &result))
+	assert.Equal(t, value, result)
+}
+
+func TestPayloadProtobuf(t *testing.T) {
+	codec := payloadCodec{codec: cache.Protobuf()}
+	payload, err := codec.encode(wrapperspb.String("value"))
+	assert.NoError(t, err)
+
+	var result wrapperspb.StringValue
+	assert.NoError(t, codec.decode(payload, &result))
+	assert.Equal(t, "value", result.GetValue())
+
+	_, err = codec.encode(payloadTest{})
+	assert.Error(t, err)
+}
+
+func TestPayloadInvalid(t *testing.T) {
+	codec := payloadCodec{}
+	var result payloadTest
+	assert.Error(t, codec.decode([]byte{payloadMagic, 9, cache.JSONCodecID, 0}, &result))
+	assert.Error(t, codec.decode([]byte{payloadMagic, payloadVersion, 200, 0}, &result))
+	assert.Error(t, codec.decode([]byte{payloadMagic}, &result))
+}
@@ -2,9 +2,10 @@ package internal_cache
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/odycenter/std-library/app/cache"
+	"github.com/odycenter/std-library/app/log/util"
 	"github.com/odycenter/std-library/app/redis"
 	redisV9 "github.com/redis/go-redis/v9"
 	"log/slog"
@@ -12,11 +13,27 @@ import (
 	"time"
 )
 
+const unlockScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`
+
+// cacheRedis is redis operations used by RedisCacheStore, implemented by internal redis client
+type cacheRedis interface {
+	redis.Redis
+	redis.ConditionalSetter
+	redis.Scripter
+	Scan(ctx context.Context, cursor uint64, match string, count int64) ([]string, uint64, error)
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	StrLen(ctx context.Context, key string) (int64, error)
+}
+
 type RedisCacheStore struct {
-	redis redis.Redis
+	redis cacheRedis
+	codec payloadCodec
 }
 
-func (c *RedisCacheStore) Initialize(redis redis.Redis) {
+func (c *RedisCacheStore) Initialize(redis cacheRedis) {
 	if c.redis != nil {
 		slog.Error("redisImpl is already configured, please configure only once")
 		return
@@ -24,6 +41,21 @@ func (c *RedisCacheStore) Initialize(redis redis.Redis) {
 	c.redis = redis
 }
 
+// Codec must be configured before start, nil codec means JSON
+func (c *RedisCacheStore) Codec(codec cache.Codec, compressionThreshold int) {
+	if codec != nil {
+		RegisterCodec(codec)
+	}
+	c.codec = payloadCodec{codec: codec, compressionThreshold: compressionThreshold}
+}
+
+// WithCodec returns store sharing same redis with different codec, used by cache with its own codec or compression
+func (c *RedisCacheStore) WithCodec(codec cache.Codec, compressionThreshold int) *RedisCacheStore {
+	store := &RedisCacheStore{redis: c.redis}
+	store.Codec(codec, compressionThreshold)
+	return store
+}
+
 func (c *RedisCacheStore) Get(ctx context.Context, key string, result interface{}) bool {
 	body, err := c.redis.Get(ctx, key)
 	if err != nil {
@@ -35,9 +67,9 @@ func (c *RedisCacheStore) Get(ctx context.Context, key string, result interface{
 		return false
 	}
 
-	err = json.Unmarshal([]byte(body), &result)
+	err = c.codec.decode([]byte(body), result)
 	if err != nil {
-		slog.ErrorContext(ctx, fmt.Sprintf("[Cache][Get] Json Unmarshal Error, key:%s, value:%s, failed:%v", key, body, err))
+		slog.ErrorContext(ctx, fmt.Sprintf("[Cache][Get] Decode Error, key:%s, value:%s, failed:%v", key, body, err))
 		return false
 	}
 
@@ -54,9 +86,9 @@ func (c *RedisCacheStore) GetAll(ctx context.Context, obj interface{}, key ...st
 	result := make(map[string]any, len(data))
 	for k, v := range data {
 		value := reflect.New(reflect.TypeOf(obj)).Interface()
-		err = json.Unmarshal([]byte(v), &value)
+		err = c.codec.decode([]byte(v), value)
 		if err != nil {
-			slog.ErrorContext(ctx, fmt.Sprintf("[Cache][GetAll] Json Unmarshal Error, key:%s, value:%s, failed:%v", k, v, err))
+			slog.ErrorContext(ctx, fmt.Sprintf("[Cache][GetAll] Decode Error, key:%s, value:%s, failed:%v", k, v, err))
 			return nil, false
 		}
 		result[k] = value
@@ -66,9 +98,9 @@ func (c *RedisCacheStore) GetAll(ctx context.Context, obj interface{}, key ...st
 }
 
 func (c *RedisCacheStore) Put(ctx context.Context, key string, obj interface{}, expiration time.Duration) bool {
-	bs, err := json.Marshal(obj)
+	bs, err := c.codec.encode(obj)
 	if err != nil {
-		slog.ErrorContext(ctx, fmt.Sprintf("[Cache][Put] Json Marshal Error, key: %s, value:%s, failed:%v", key, obj, err))
+		slog.ErrorContext(ctx, fmt.Sprintf("[Cache][Put] Encode Error, key: %s, value:%s, failed:%v", key, obj, err))
 		return false
 	}
 
@@ -82,9 +114,9 @@ func (c *RedisCacheStore) Put(ctx context.Context, key string, obj interface{},
 func (c *RedisCacheStore) PutAll(ctx context.Context, values map[string]any, expiration time.Duration) bool {
 	data := make(map[string]any, len(values))
 	for k, v := range values {
-		bs, err := json.Marshal(v)
+		bs, err := c.codec.encode(v)
 		if err != nil {
-			slog.ErrorContext(ctx, fmt.Sprintf("[Cache][PutAll] Json Marshal Error, key: %s, value:%s, failed:%v", k, v, err))
+			slog.ErrorContext(ctx, fmt.Sprintf("[Cache][PutAll] Encode Error, key: %s, value:%s, failed:%v", k, v, err))
 			return false
 		}
 		data[k] = string(bs)
@@ -104,3 +136,37 @@ func (c *RedisCacheStore) Delete(ctx context.Context, key ...string) bool {
 	}
 	return err == nil
 }
+
+func (c *RedisCacheStore) Lock(ctx context.Context, key string, expiration time.Duration) (string, bool) {
+	token := util.GetIDGenerator().Next(time.Now())
+	success, err := c.redis.SetNX(ctx, key, token, expiration)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[Cache][Lock] key: %s, failed:%v", key, err))
+	}
+	return token, success
+}
+
+// Unlock deletes lock only if it is still held by token, lock may be expired and acquired by others
+func (c *RedisCacheStore) Unlock(ctx context.Context, key string, token string) {
+	_, err := c.redis.Eval(ctx, unlockScript, []string{key}, token)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[Cache][Unlock] key: %s, failed:%v", key, err))
+	}
+}
+
+func (c *RedisCacheStore) Scan(ctx context.Context, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return c.redis.Scan(ctx, cursor, pattern, count)
+}
+
+func (c *RedisCacheStore) Inspect(ctx context.Context, key string) (KeyInfo, bool) {
+	ttl, err := c.redis.TTL(ctx, key)
+	if err != nil || ttl == -2 { // -2 means key does not exist
+		return KeyInfo{}, false
+	}
+	size, err := c.redis.StrLen(ctx, key)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("[Cache][Inspect] key: %s, failed:%v", key, err))
+		return KeyInfo{}, false
+	}
+	return KeyInfo{Key: key, TTL: ttl, Size: size}, true
+}
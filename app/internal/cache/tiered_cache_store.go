@@ -0,0 +1,211 @@
+package internal_cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/odycenter/std-library/app/cache"
+	"github.com/odycenter/std-library/app/log/util"
+	"github.com/odycenter/std-library/app/redis"
+	redisV9 "github.com/redis/go-redis/v9"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	invalidationChannel             = "cache:invalidation"
+	defaultTieredMaxLocalExpiration = time.Minute
+)
+
+// TieredCacheStore keeps a bounded local copy in front of redis,
+// Put/Delete publish invalidation message to redis, and all instances drop their local copies on receiving.
+// local expiration is capped by MaxLocalExpiration, to bound staleness in case invalidation message is lost (e.g. redis reconnect)
+type TieredCacheStore struct {
+	MaxLocalExpiration time.Duration
+	local              *LocalCacheStore
+	remote             *RedisCacheStore
+	redis              PubSubRedis
+	instanceId         string
+	pubSub             *redisV9.PubSub
+	wg                 sync.WaitGroup
+}
+
+// PubSubRedis is redis with pub/sub, which is kept out of public redis.Redis
+type PubSubRedis interface {
+	redis.Redis
+	Publish(ctx context.Context, channel string, message string) (int64, error)
+	Subscribe(ctx context.Context, channel ...string) *redisV9.PubSub
+}
+
+type invalidationMessage struct {
+	Sender string   `json:"sender"`
+	Keys   []string `json:"keys"`
+}
+
+func NewTieredCacheStore(local *LocalCacheStore, remote *RedisCacheStore, redis PubSubRedis) *TieredCacheStore {
+	return &TieredCacheStore{
+		MaxLocalExpiration: defaultTieredMaxLocalExpiration,
+		local:              local,
+		remote:             remote,
+		redis:              redis,
+		instanceId:         util.GetIDGenerator().Next(time.Now()),
+	}
+}
+
+// WithCodec returns store sharing local copies and invalidation subscription of c, with different codec for redis
+func (c *TieredCacheStore) WithCodec(codec cache.Codec, compressionThreshold int) *TieredCacheStore {
+	return &TieredCacheStore{
+		MaxLocalExpiration: c.MaxLocalExpiration,
+		local:              c.local,
+		remote:             c.remote.WithCodec(codec, compressionThreshold),
+		redis:              c.redis,
+		instanceId:         c.instanceId,
+	}
+}
+
+func (c *TieredCacheStore) Execute(ctx context.Context) {
+	c.Subscribe(ctx)
+}
+
+// Subscribe starts to listen invalidation messages from other instances
+func (c *TieredCacheStore) Subscribe(ctx context.Context) {
+	c.pubSub = c.redis.Subscribe(context.Background(), invalidationChannel)
+	channel := c.pubSub.Channel()
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for message := range channel {
+			c.invalidate(message.Payload)
+		}
+	}()
+	slog.InfoContext(ctx, fmt.Sprintf("subscribe cache invalidation, channel=%s", invalidationChannel))
+}
+
+func (c *TieredCacheStore) Close() {
+	if c.pubSub == nil {
+		return
+	}
+	err := c.pubSub.Close()
+	if err != nil {
+		slog.Error(fmt.Sprintf("close cache invalidation subscription, failed:%v", err))
+	}
+	c.wg.Wait()
+}
+
+func (c *TieredCacheStore) Get(ctx context.Context, key string, result interface{}) bool {
+	if c.local.Get(ctx, key, result) {
+		stat(ctx, "cache_local_hits", 1)
+		return true
+	}
+	stat(ctx, "cache_local_misses", 1)
+
+	if !c.remote.Get(ctx, key, result) {
+		stat(ctx, "cache_remote_misses", 1)
+		return false
+	}
+	stat(ctx, "cache_remote_hits", 1)
+	c.local.Put(ctx, key, result, c.MaxLocalExpiration)
+	return true
+}
+
+func (c *TieredCacheStore) GetAll(ctx context.Context, obj interface{}, key ...string) (map[string]any, bool) {
+	result, _ := c.local.GetAll(ctx, obj, key...)
+	stat(ctx, "cache_local_hits", float64(len(result)))
+	var missingKeys []string
+	for _, k := range key {
+		if _, ok := result[k]; !ok {
+			missingKeys = append(missingKeys, k)
+		}
+	}
+	if len(missingKeys) == 0 {
+		return result, true
+	}
+	stat(ctx, "cache_local_misses", float64(len(missingKeys)))
+
+	remoteValues, ok := c.remote.GetAll(ctx, obj, missingKeys...)
+	if !ok {
+		return result, false
+	}
+	stat(ctx, "cache_remote_hits", float64(len(remoteValues)))
+	stat(ctx, "cache_remote_misses", float64(len(missingKeys)-len(remoteValues)))
+	if len(remoteValues) > 0 {
+		c.local.PutAll(ctx, remoteValues, c.MaxLocalExpiration)
+	}
+	for k, v := range remoteValues {
+		result[k] = v
+	}
+	return result, true
+}
+
+func (c *TieredCacheStore) Put(ctx context.Context, key string, obj interface{}, expiration time.Duration) bool {
+	if !c.remote.Put(ctx, key, obj, expiration) {
+		return false
+	}
+	c.publish(ctx, key)
+	return c.local.Put(ctx, key, obj, c.localExpiration(expiration))
+}
+
+func (c *TieredCacheStore) PutAll(ctx context.Context, values map[string]any, expiration time.Duration) bool {
+	if !c.remote.PutAll(ctx, values, expiration) {
+		return false
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	c.publish(ctx, keys...)
+	return c.local.PutAll(ctx, values, c.localExpiration(expiration))
+}
+
+func (c *TieredCacheStore) Delete(ctx context.Context, key ...string) bool {
+	c.local.Delete(ctx, key...)
+	success := c.remote.Delete(ctx, key...)
+	c.publish(ctx, key...)
+	return success
+}
+
+func (c *TieredCacheStore) Lock(ctx context.Context, key string, expiration time.Duration) (string, bool) {
+	return c.remote.Lock(ctx, key, expiration)
+}
+
+func (c *TieredCacheStore) Unlock(ctx context.Context, key string, token string) {
+	c.remote.Unlock(ctx, key, token)
+}
+
+func (c *TieredCacheStore) Scan(ctx context.Context, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
+	return c.remote.Scan(ctx, pattern, cursor, count)
+}
+
+func (c *TieredCacheStore) Inspect(ctx context.Context, key string) (KeyInfo, bool) {
+	return c.remote.Inspect(ctx, key)
+}
+
+func (c *TieredCacheStore) localExpiration(expiration time.Duration) time.Duration {
+	if c.MaxLocalExpiration > 0 && expiration > c.MaxLocalExpiration {
+		return c.MaxLocalExpiration
+	}
+	return expiration
+}
+
+func (c *TieredCacheStore) publish(ctx context.Context, key ...string) {
+	message, _ := json.Marshal(invalidationMessage{Sender: c.instanceId, Keys: key})
+	_, err := c.redis.Publish(ctx, invalidationChannel, string(message))
+	if err != nil {
+		slog.WarnContext(ctx, fmt.Sprintf("[Cache][Publish] failed to publish invalidation, keys:%v, failed:%v", key, err))
+	}
+}
+
+func (c *TieredCacheStore) invalidate(payload string) {
+	var message invalidationMessage
+	err := json.Unmarshal([]byte(payload), &message)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("[Cache][Invalidate] invalid message, payload:%s, failed:%v", payload, err))
+		return
+	}
+	if message.Sender == c.instanceId {
+		return
+	}
+	slog.Debug(fmt.Sprintf("[Cache][Invalidate] keys:%v", message.Keys))
+	c.local.Delete(context.Background(), message.Keys...)
+}
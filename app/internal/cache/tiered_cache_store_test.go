@@ -0,0 +1,192 @@
+package internal_cache
+
+import (
+	"context"
+	"errors"
+	redisV9 "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis shares values between stores, and delivers published messages synchronously to subscribers
+type fakeRedis struct {
+	values      map[string]string
+	subscribers []*TieredCacheStore
+	mu          sync.Mutex
+}
+
+func (r *fakeRedis) Close() {}
+
+func (r *fakeRedis) Get(_ context.Context, key string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	value, ok := r.values[key]
+	if !ok {
+		return "", redisV9.Nil
+	}
+	return value, nil
+}
+
+func (r *fakeRedis) MultiGet(_ context.Context, key ...string) (map[string]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := make(map[string]string)
+	for _, k := range key {
+		if value, ok := r.values[k]; ok {
+			result[k] = value
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeRedis) Set(_ context.Context, key string, value string, _ time.Duration) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.values[key] = value
+	return "OK", nil
+}
+
+func (r *fakeRedis) SetNX(_ context.Context, key string, value string, _ time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.values[key]; ok {
+		return false, nil
+	}
+	r.values[key] = value
+	return true, nil
+}
+
+func (r *fakeRedis) MultiSet(_ context.Context, values map[string]interface{}, _ time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for k, v := range values {
+		r.values[k] = v.(string)
+	}
+	return nil
+}
+
+func (r *fakeRedis) Del(_ context.Context, keys ...string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, k := range keys {
+		delete(r.values, k)
+	}
+	return int64(len(keys)), nil
+}
+
+// Scan returns all matched keys in one batch
+func (r *fakeRedis) Scan(_ context.Context, _ uint64, match string, _ int64) ([]string, uint64, error) {
+	matcher, err := globRegexp(match)
+	if err != nil {
+		return nil, 0, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var keys []string
+	for k := range r.values {
+		if matcher.MatchString(k) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, 0, nil
+}
+
+func (r *fakeRedis) TTL(_ context.Context, key string) (time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.values[key]; !ok {
+		return -2, nil
+	}
+	return time.Hour, nil
+}
+
+func (r *fakeRedis) StrLen(_ context.Context, key string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return int64(len(r.values[key])), nil
+}
+
+// Eval only supports unlockScript
+func (r *fakeRedis) Eval(_ context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	if script != unlockScript {
+		return nil, errors.New("not supported")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if value, ok := r.values[keys[0]]; ok && value == args[0] {
+		delete(r.values, keys[0])
+		return int64(1), nil
+	}
+	return int64(0), nil
+}
+
+func (r *fakeRedis) Publish(_ context.Context, _ string, message string) (int64, error) {
+	for _, subscriber := range r.subscribers {
+		subscriber.invalidate(message)
+	}
+	return int64(len(r.subscribers)), nil
+}
+
+func (r *fakeRedis) Subscribe(_ context.Context, _ ...string) *redisV9.PubSub {
+	return nil
+}
+
+type tieredCacheTest struct {
+	Name string
+}
+
+func newTieredCacheStore(redis *fakeRedis) *TieredCacheStore {
+	remote := &RedisCacheStore{}
+	remote.Initialize(redis)
+	store := NewTieredCacheStore(NewLocalCacheStore(), remote, redis)
+	redis.subscribers = append(redis.subscribers, store)
+	return store
+}
+
+func TestTieredCacheStoreInvalidation(t *testing.T) {
+	ctx := context.Background()
+	redis := &fakeRedis{values: make(map[string]string)}
+	instance1 := newTieredCacheStore(redis)
+	instance2 := newTieredCacheStore(redis)
+
+	assert.True(t, instance1.Put(ctx, "key", tieredCacheTest{Name: "v1"}, time.Hour))
+	var result tieredCacheTest
+	assert.True(t, instance2.Get(ctx, "key", &result))
+	assert.Equal(t, "v1", result.Name)
+	assert.Equal(t, 1, instance2.local.Size())
+
+	assert.True(t, instance1.Put(ctx, "key", tieredCacheTest{Name: "v2"}, time.Hour))
+	assert.Equal(t, 1, instance1.local.Size(), "own local copy must not be invalidated")
+	assert.Equal(t, 0, instance2.local.Size())
+	assert.True(t, instance2.Get(ctx, "key", &result))
+	assert.Equal(t, "v2", result.Name)
+
+	assert.True(t, instance1.Delete(ctx, "key"))
+	assert.False(t, instance2.Get(ctx, "key", &result))
+}
+
+func TestTieredCacheStoreGetAll(t *testing.T) {
+	ctx := context.Background()
+	redis := &fakeRedis{values: make(map[string]string)}
+	instance1 := newTieredCacheStore(redis)
+	instance2 := newTieredCacheStore(redis)
+
+	instance1.PutAll(ctx, map[string]any{"key1": tieredCacheTest{Name: "1"}, "key2": tieredCacheTest{Name: "2"}}, time.Hour)
+	var result tieredCacheTest
+	instance2.Get(ctx, "key1", &result)
+
+	values, ok := instance2.GetAll(ctx, tieredCacheTest{}, "key1", "key2", "key3")
+	assert.True(t, ok)
+	assert.Len(t, values, 2)
+	assert.Equal(t, tieredCacheTest{Name: "1"}, values["key1"])
+	assert.Equal(t, &tieredCacheTest{Name: "2"}, values["key2"])
+	assert.Equal(t, 2, instance2.local.Size())
+}
+
+func TestTieredCacheStoreLocalExpiration(t *testing.T) {
+	store := NewTieredCacheStore(NewLocalCacheStore(), nil, nil)
+	assert.Equal(t, time.Minute, store.localExpiration(time.Hour))
+	assert.Equal(t, time.Second, store.localExpiration(time.Second))
+}
@@ -1,10 +1,49 @@
 package internal_cache
 
 import (
+	"context"
+	actionlog "github.com/odycenter/std-library/app/log"
 	reflects "github.com/odycenter/std-library/reflect"
+	"regexp"
 	"strings"
 )
 
 func cacheName(obj interface{}) string {
 	return strings.ToLower(reflects.StructName(obj))
 }
+
+// stat accumulates value into action log stat of ctx
+func stat(ctx context.Context, key string, value float64) {
+	val := actionlog.GetStat(&ctx, key)
+	if val != 0 {
+		value += val
+	}
+	actionlog.Stat(&ctx, key, value)
+}
+
+// globRegexp converts redis style glob pattern (*, ?, [...]) to regexp
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	var builder strings.Builder
+	builder.WriteString("^")
+	inClass := false
+	for _, r := range pattern {
+		switch {
+		case inClass:
+			if r == ']' {
+				inClass = false
+			}
+			builder.WriteRune(r)
+		case r == '*':
+			builder.WriteString(".*")
+		case r == '?':
+			builder.WriteString(".")
+		case r == '[':
+			inClass = true
+			builder.WriteRune(r)
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	builder.WriteString("$")
+	return regexp.Compile(builder.String())
+}
@@ -1,22 +1,84 @@
 package internal
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"github.com/odycenter/std-library/app/async"
+	"log/slog"
+	"sync"
+	"time"
+)
 
 type BackgroundTaskExecutor struct {
+	tasks []backgroundTask
+	stop  chan struct{}
+	wg    sync.WaitGroup
 }
 
-func (b *BackgroundTaskExecutor) Start() {
+type backgroundTask struct {
+	name  string
+	task  func(ctx context.Context)
+	delay time.Duration
+}
 
+func NewBackgroundTaskExecutor() *BackgroundTaskExecutor {
+	return &BackgroundTaskExecutor{
+		stop: make(chan struct{}),
+	}
 }
 
-func (b *BackgroundTaskExecutor) ScheduleWithFixedDelay() {
+func (b *BackgroundTaskExecutor) Execute(_ context.Context) {
+	b.Start()
+}
 
+func (b *BackgroundTaskExecutor) Start() {
+	for _, task := range b.tasks {
+		b.wg.Add(1)
+		go b.run(task)
+	}
+	if len(b.tasks) > 0 {
+		slog.Info(fmt.Sprintf("background task executor started, tasks=%d", len(b.tasks)))
+	}
 }
 
-func (b *BackgroundTaskExecutor) Shutdown() {
+// ScheduleWithFixedDelay must be called before Start, each run is executed with its own action log
+func (b *BackgroundTaskExecutor) ScheduleWithFixedDelay(name string, task func(ctx context.Context), delay time.Duration) {
+	b.tasks = append(b.tasks, backgroundTask{name: name, task: task, delay: delay})
+}
 
+func (b *BackgroundTaskExecutor) Shutdown() {
+	slog.Info("shutting down background task executor")
+	close(b.stop)
 }
 
 func (b *BackgroundTaskExecutor) AwaitTermination(ctx context.Context, timeoutInMs int64) {
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		slog.InfoContext(ctx, "background task executor stopped")
+	case <-time.After(time.Duration(timeoutInMs) * time.Millisecond):
+		slog.WarnContext(ctx, "[FAILED_TO_STOP] failed to terminate background task executor, due to timeout")
+	}
+}
 
+func (b *BackgroundTaskExecutor) run(task backgroundTask) {
+	defer b.wg.Done()
+	timer := time.NewTimer(task.delay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-timer.C:
+			wg := sync.WaitGroup{}
+			async.RunFuncWithName(nil, task.name, task.task, &wg)
+			wg.Wait()
+			timer.Reset(task.delay)
+		}
+	}
 }
@@ -147,6 +147,10 @@ func (r *RedisImpl) Set(ctx context.Context, key string, value string, expiratio
 	return r.client.SetArgs(ctx, key, value, arg).Result()
 }
 
+func (r *RedisImpl) SetNX(ctx context.Context, key string, value string, expiration time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, key, value, expiration).Result()
+}
+
 func (r *RedisImpl) MultiSet(ctx context.Context, values map[string]interface{}, expiration time.Duration) error {
 	arg := redis.SetArgs{
 		TTL: expiration,
@@ -163,3 +167,27 @@ func (r *RedisImpl) MultiSet(ctx context.Context, values map[string]interface{},
 func (r *RedisImpl) Del(ctx context.Context, keys ...string) (int64, error) {
 	return r.client.Del(ctx, keys...).Result()
 }
+
+func (r *RedisImpl) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return r.client.Eval(ctx, script, keys, args...).Result()
+}
+
+func (r *RedisImpl) Scan(ctx context.Context, cursor uint64, match string, count int64) ([]string, uint64, error) {
+	return r.client.Scan(ctx, cursor, match, count).Result()
+}
+
+func (r *RedisImpl) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return r.client.TTL(ctx, key).Result()
+}
+
+func (r *RedisImpl) StrLen(ctx context.Context, key string) (int64, error) {
+	return r.client.StrLen(ctx, key).Result()
+}
+
+func (r *RedisImpl) Publish(ctx context.Context, channel string, message string) (int64, error) {
+	return r.client.Publish(ctx, channel, message).Result()
+}
+
+func (r *RedisImpl) Subscribe(ctx context.Context, channel ...string) *redis.PubSub {
+	return r.client.Subscribe(ctx, channel...)
+}
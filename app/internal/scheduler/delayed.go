@@ -0,0 +1,206 @@
+package internal_scheduler
+
+import (
+	"context"
+	"fmt"
+	actionlog "github.com/odycenter/std-library/app/log"
+	"github.com/odycenter/std-library/app/log/util"
+	"github.com/odycenter/std-library/app/scheduler"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	delayedPollInterval = time.Second
+	delayedClaimLimit   = 100
+	delayedClaimTimeout = time.Minute // claim is extended while job is running, job is claimed again if instance crashed
+	delayedMaxAttempts  = 3
+	delayedRetryBackoff = time.Minute // doubled on each failed attempt
+	delayedParkAfter    = time.Hour   // job with unknown handler is parked if not claimed by other instance in time, e.g. during rolling deploy
+	delayedStoreTimeout = 5 * time.Second
+)
+
+// DelayedJobStore enables delayed jobs, which are polled and executed by any node of cluster
+func (s *SchedulerImpl) DelayedJobStore(store scheduler.DelayedJobStore) {
+	s.delayedStore = store
+}
+
+func (s *SchedulerImpl) AddDelayedHandler(name string, handler func(ctx context.Context, payload string)) {
+	if _, ok := s.delayedHandlers[name]; ok {
+		panic("delayed job handler already exists, name=" + name)
+	}
+	s.delayedHandlers[name] = handler
+}
+
+// ScheduleAt stores job which runs once at runAt by handler, returns id of job
+func (s *SchedulerImpl) ScheduleAt(ctx context.Context, handler string, runAt time.Time, payload string) (string, error) {
+	if s.delayedStore == nil {
+		return "", fmt.Errorf("delayed job store is not configured")
+	}
+	if _, ok := s.delayedHandlers[handler]; !ok {
+		return "", fmt.Errorf("delayed job handler not found, handler=%s", handler)
+	}
+	now := time.Now()
+	job := &scheduler.DelayedJob{
+		Id:        util.GetIDGenerator().Next(now),
+		Handler:   handler,
+		Payload:   payload,
+		RunAt:     runAt,
+		RefId:     actionlog.GetId(&ctx),
+		CreatedAt: now,
+	}
+	if err := s.delayedStore.Schedule(ctx, job); err != nil {
+		return "", err
+	}
+	actionlog.Context(&ctx, "delayed_job_id", job.Id)
+	return job.Id, nil
+}
+
+func (s *SchedulerImpl) DelayedJobs(ctx context.Context, limit int) ([]*scheduler.DelayedJob, error) {
+	if s.delayedStore == nil {
+		return nil, nil
+	}
+	return s.delayedStore.List(ctx, limit)
+}
+
+func (s *SchedulerImpl) CancelDelayed(ctx context.Context, id string) (bool, error) {
+	if s.delayedStore == nil {
+		return false, nil
+	}
+	return s.delayedStore.Cancel(ctx, id)
+}
+
+func (s *SchedulerImpl) RescheduleDelayed(ctx context.Context, id string, runAt time.Time) (bool, error) {
+	if s.delayedStore == nil {
+		return false, nil
+	}
+	return s.delayedStore.Reschedule(ctx, id, runAt)
+}
+
+func (s *SchedulerImpl) pollDelayed() {
+	defer close(s.delayedPollDone)
+	ticker := time.NewTicker(delayedPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopping:
+			return
+		case <-ticker.C:
+			s.claimDelayed()
+		}
+	}
+}
+
+func (s *SchedulerImpl) claimDelayed() {
+	ctx, cancel := context.WithTimeout(context.Background(), delayedStoreTimeout)
+	defer cancel()
+	jobs, err := s.delayedStore.Claim(ctx, time.Now(), delayedClaimLimit, s.delayedClaimTimeout)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("failed to claim delayed jobs, error: %v", err))
+		return
+	}
+	for _, job := range jobs {
+		handler, ok := s.delayedHandlers[job.Handler]
+		if !ok {
+			s.handlerNotFound(job)
+			continue
+		}
+		s.delayedRunning.Add(1)
+		atomic.AddInt32(&s.runningTaskCount, 1)
+		go func() {
+			defer s.delayedRunning.Done()
+			defer atomic.AddInt32(&s.runningTaskCount, -1)
+			s.runDelayed(job, handler)
+		}()
+	}
+}
+
+// handlerNotFound leaves job claimed, so other instance with newer version may claim it after timeout, job is parked if it's overdue too long
+func (s *SchedulerImpl) handlerNotFound(job *scheduler.DelayedJob) {
+	if time.Since(job.RunAt) < delayedParkAfter {
+		slog.Warn(fmt.Sprintf("delayed job handler not found, it will be claimed again, id: %s, handler: %s", job.Id, job.Handler))
+		return
+	}
+	slog.Error(fmt.Sprintf("[DELAYED_JOB_PARKED] delayed job handler not found, park job, reschedule it after handler is deployed, id: %s, handler: %s", job.Id, job.Handler))
+	s.parkDelayed(job)
+}
+
+func (s *SchedulerImpl) runDelayed(job *scheduler.DelayedJob, handler func(ctx context.Context, payload string)) {
+	process := func(ctx context.Context) {
+		actionlog.Context(&ctx, "delayed_job_id", job.Id)
+		actionlog.Context(&ctx, "delayed_job_run_at", job.RunAt)
+		actionlog.Context(&ctx, "delayed_job_attempts", job.Attempts+1)
+		handler(ctx, job.Payload)
+	}
+	stopExtending := s.extendDelayed(job.Id)
+	execution, executed := create("delayed:"+job.Handler, process, job.RefId)
+	stopExtending()
+	if !executed {
+		return // rejected due to shutdown, it will be claimed again after timeout
+	}
+	h, _ := s.histories.LoadOrStore("delayed:"+job.Handler, &history{})
+	h.(*history).record("delayed:"+job.Handler, *execution)
+
+	if execution.Result != "ok" {
+		s.retryDelayed(job)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), delayedStoreTimeout)
+	defer cancel()
+	if err := s.delayedStore.Complete(ctx, job.Id); err != nil {
+		slog.Warn(fmt.Sprintf("failed to complete delayed job, id: %s, error: %v", job.Id, err))
+	}
+}
+
+// extendDelayed extends claim periodically until returned func is called, so long-running job is not claimed by other instance
+func (s *SchedulerImpl) extendDelayed(id string) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(s.delayedClaimTimeout / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), delayedStoreTimeout)
+				_, err := s.delayedStore.Extend(ctx, id, time.Now().Add(s.delayedClaimTimeout))
+				cancel()
+				if err != nil {
+					slog.Warn(fmt.Sprintf("failed to extend delayed job claim, id: %s, error: %v", id, err))
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// retryDelayed reschedules failed job with exponential backoff, job is parked after max attempts
+func (s *SchedulerImpl) retryDelayed(job *scheduler.DelayedJob) {
+	job.Attempts++
+	if job.Attempts >= delayedMaxAttempts {
+		slog.Error(fmt.Sprintf("[DELAYED_JOB_PARKED] delayed job failed after max attempts, park job, id: %s, handler: %s, attempts: %d", job.Id, job.Handler, job.Attempts))
+		s.parkDelayed(job)
+		return
+	}
+	job.RunAt = time.Now().Add(delayedRetryBackoff << (job.Attempts - 1))
+	ctx, cancel := context.WithTimeout(context.Background(), delayedStoreTimeout)
+	defer cancel()
+	if err := s.delayedStore.Schedule(ctx, job); err != nil {
+		slog.Warn(fmt.Sprintf("failed to retry delayed job, it will be claimed again after timeout, id: %s, error: %v", job.Id, err))
+	}
+}
+
+func (s *SchedulerImpl) parkDelayed(job *scheduler.DelayedJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), delayedStoreTimeout)
+	defer cancel()
+	if err := s.delayedStore.Park(ctx, job); err != nil {
+		slog.Warn(fmt.Sprintf("failed to park delayed job, it will be claimed again after timeout, id: %s, error: %v", job.Id, err))
+	}
+}
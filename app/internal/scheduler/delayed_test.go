@@ -0,0 +1,243 @@
+package internal_scheduler
+
+import (
+	"context"
+	"github.com/odycenter/std-library/app/scheduler"
+	"github.com/odycenter/std-library/app/web/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryDelayedJobStore struct {
+	mu      sync.Mutex
+	jobs    map[string]*scheduler.DelayedJob
+	extends int
+}
+
+func newMemoryDelayedJobStore() *memoryDelayedJobStore {
+	return &memoryDelayedJobStore{jobs: map[string]*scheduler.DelayedJob{}}
+}
+
+func (s *memoryDelayedJobStore) Schedule(_ context.Context, job *scheduler.DelayedJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *job
+	s.jobs[job.Id] = &copied
+	return nil
+}
+
+func (s *memoryDelayedJobStore) Claim(_ context.Context, now time.Time, limit int, timeout time.Duration) ([]*scheduler.DelayedJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []*scheduler.DelayedJob
+	for _, job := range s.jobs {
+		if len(result) < limit && !job.Parked && !job.RunAt.After(now) {
+			copied := *job
+			result = append(result, &copied)
+			job.RunAt = now.Add(timeout)
+		}
+	}
+	return result, nil
+}
+
+func (s *memoryDelayedJobStore) Extend(_ context.Context, id string, until time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if ok && !job.Parked {
+		job.RunAt = until
+		s.extends++
+	}
+	return ok, nil
+}
+
+func (s *memoryDelayedJobStore) Park(_ context.Context, job *scheduler.DelayedJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *job
+	copied.Parked = true
+	s.jobs[job.Id] = &copied
+	return nil
+}
+
+func (s *memoryDelayedJobStore) job(id string) *scheduler.DelayedJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jobs[id]
+}
+
+func (s *memoryDelayedJobStore) Complete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+	return nil
+}
+
+func (s *memoryDelayedJobStore) List(_ context.Context, limit int) ([]*scheduler.DelayedJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []*scheduler.DelayedJob
+	for _, job := range s.jobs {
+		copied := *job
+		result = append(result, &copied)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].RunAt.Before(result[j].RunAt) })
+	return result, nil
+}
+
+func (s *memoryDelayedJobStore) Cancel(_ context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.jobs[id]
+	delete(s.jobs, id)
+	return ok, nil
+}
+
+func (s *memoryDelayedJobStore) Reschedule(_ context.Context, id string, runAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if ok {
+		if job.Parked {
+			job.Attempts = 0
+		}
+		job.RunAt = runAt
+		job.Parked = false
+	}
+	return ok, nil
+}
+
+func TestDelayedJobs(t *testing.T) {
+	s := New()
+	store := newMemoryDelayedJobStore()
+	s.DelayedJobStore(store)
+	payloads := make(chan string, 10)
+	s.AddDelayedHandler("payout", func(ctx context.Context, payload string) {
+		payloads <- payload
+	})
+
+	ctx := context.Background()
+	_, err := s.ScheduleAt(ctx, "unknown", time.Now(), "")
+	assert.ErrorContains(t, err, "handler not found")
+
+	due, err := s.ScheduleAt(ctx, "payout", time.Now().Add(-time.Second), "due")
+	require.NoError(t, err)
+	later, err := s.ScheduleAt(ctx, "payout", time.Now().Add(time.Hour), "later")
+	require.NoError(t, err)
+	canceled, err := s.ScheduleAt(ctx, "payout", time.Now().Add(time.Hour), "canceled")
+	require.NoError(t, err)
+
+	jobs, err := s.DelayedJobs(ctx, 10)
+	require.NoError(t, err)
+	assert.Len(t, jobs, 3)
+	ok, err := s.CancelDelayed(ctx, canceled)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	ok, _ = s.CancelDelayed(ctx, "not-exist")
+	assert.False(t, ok)
+
+	s.claimDelayed()
+	s.delayedRunning.Wait()
+	assert.Equal(t, "due", <-payloads)
+	assert.NotContains(t, store.jobs, due, "completed job is removed")
+
+	ok, err = s.RescheduleDelayed(ctx, later, time.Now().Add(-time.Second))
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	s.claimDelayed()
+	s.delayedRunning.Wait()
+	assert.Equal(t, "later", <-payloads)
+	assert.Empty(t, store.jobs)
+
+	executions, _ := s.history("delayed:payout")
+	assert.Len(t, executions, 2)
+}
+
+func TestFailedDelayedJobIsRetriedThenParked(t *testing.T) {
+	s := New()
+	store := newMemoryDelayedJobStore()
+	s.DelayedJobStore(store)
+	s.AddDelayedHandler("reminder", func(ctx context.Context, payload string) {
+		errors.Internal("failed to push reminder", "PUSH_FAILED")
+	})
+	id, err := s.ScheduleAt(context.Background(), "reminder", time.Now(), "")
+	require.NoError(t, err)
+
+	s.claimDelayed()
+	s.delayedRunning.Wait()
+	job := store.job(id)
+	assert.Equal(t, 1, job.Attempts)
+	assert.WithinDuration(t, time.Now().Add(delayedRetryBackoff), job.RunAt, time.Second, "retried after backoff")
+	executions, failures := s.history("delayed:reminder")
+	require.Len(t, executions, 1)
+	assert.Equal(t, "PUSH_FAILED", executions[0].ErrorCode)
+	assert.Equal(t, 1, failures)
+
+	for i := 1; i < delayedMaxAttempts; i++ {
+		_, _ = s.RescheduleDelayed(context.Background(), id, time.Now())
+		s.claimDelayed()
+		s.delayedRunning.Wait()
+	}
+	job = store.job(id)
+	assert.True(t, job.Parked, "parked after max attempts")
+	assert.Equal(t, delayedMaxAttempts, job.Attempts)
+	s.claimDelayed()
+	s.delayedRunning.Wait()
+	executions, _ = s.history("delayed:reminder")
+	assert.Len(t, executions, delayedMaxAttempts, "parked job is not claimed")
+
+	ok, err := s.RescheduleDelayed(context.Background(), id, time.Now())
+	require.True(t, ok)
+	require.NoError(t, err)
+	assert.Zero(t, store.job(id).Attempts, "attempts are reset after parked job is rescheduled")
+	s.claimDelayed()
+	s.delayedRunning.Wait()
+	job = store.job(id)
+	assert.False(t, job.Parked, "retried again after rescheduled")
+	assert.Equal(t, 1, job.Attempts)
+}
+
+func TestDelayedJobHandlerNotFound(t *testing.T) {
+	s := New()
+	store := newMemoryDelayedJobStore()
+	s.DelayedJobStore(store)
+	ctx := context.Background()
+	_ = store.Schedule(ctx, &scheduler.DelayedJob{Id: "recent", Handler: "unknown", RunAt: time.Now()})
+	_ = store.Schedule(ctx, &scheduler.DelayedJob{Id: "overdue", Handler: "unknown", RunAt: time.Now().Add(-2 * delayedParkAfter)})
+
+	s.claimDelayed()
+	assert.False(t, store.job("recent").Parked, "left claimed for other instance")
+	assert.True(t, store.job("recent").RunAt.After(time.Now()))
+	assert.True(t, store.job("overdue").Parked)
+
+	jobs, err := s.DelayedJobs(ctx, 10)
+	require.NoError(t, err)
+	assert.Len(t, jobs, 2, "parked job is listed")
+}
+
+func TestDelayedJobClaimExtended(t *testing.T) {
+	s := New()
+	s.delayedClaimTimeout = 30 * time.Millisecond
+	store := newMemoryDelayedJobStore()
+	s.DelayedJobStore(store)
+	s.AddDelayedHandler("report", func(ctx context.Context, payload string) {
+		time.Sleep(100 * time.Millisecond)
+	})
+	id, err := s.ScheduleAt(context.Background(), "report", time.Now(), "")
+	require.NoError(t, err)
+
+	s.claimDelayed()
+	time.Sleep(60 * time.Millisecond)
+	s.claimDelayed()
+	s.delayedRunning.Wait()
+	executions, _ := s.history("delayed:report")
+	assert.Len(t, executions, 1, "running job is not claimed again")
+	assert.Nil(t, store.job(id))
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	assert.Positive(t, store.extends)
+}
@@ -0,0 +1,51 @@
+package internal_scheduler
+
+import (
+	"github.com/odycenter/std-library/app/scheduler"
+	"github.com/odycenter/std-library/app/web/metric"
+	"sync"
+)
+
+const historySize = 20
+
+// history is bounded ring of recent executions of job
+type history struct {
+	mu                  sync.Mutex
+	executions          [historySize]scheduler.Execution
+	next                int
+	count               int
+	consecutiveFailures int
+}
+
+func (h *history) record(job string, execution scheduler.Execution) {
+	h.mu.Lock()
+	h.executions[h.next] = execution
+	h.next = (h.next + 1) % historySize
+	if h.count < historySize {
+		h.count++
+	}
+	if execution.Result == "ok" {
+		h.consecutiveFailures = 0
+	} else {
+		h.consecutiveFailures++
+	}
+	failures := h.consecutiveFailures
+	h.mu.Unlock()
+
+	metric.JobDuration.WithLabelValues(job, execution.Result).Observe(execution.Duration.Seconds())
+	if execution.Result != "ok" {
+		metric.JobFailed.WithLabelValues(job, execution.ErrorCode).Inc()
+	}
+	metric.JobConsecutiveFailures.WithLabelValues(job).Set(float64(failures))
+}
+
+// snapshot returns executions latest first and consecutive failures
+func (h *history) snapshot() ([]scheduler.Execution, int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	executions := make([]scheduler.Execution, 0, h.count)
+	for i := 1; i <= h.count; i++ {
+		executions = append(executions, h.executions[(h.next-i+historySize)%historySize])
+	}
+	return executions, h.consecutiveFailures
+}
@@ -0,0 +1,59 @@
+package internal_scheduler
+
+import (
+	"context"
+	"fmt"
+	"github.com/odycenter/std-library/app/scheduler"
+	"github.com/odycenter/std-library/app/web/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestHistoryRing(t *testing.T) {
+	h := &history{}
+	for i := 0; i < historySize+5; i++ {
+		h.record("ring", scheduler.Execution{Result: "ok", ActionId: fmt.Sprint(i)})
+	}
+	h.record("ring", scheduler.Execution{Result: "error", ErrorCode: "INTERNAL_ERROR", ActionId: "last"})
+	h.record("ring", scheduler.Execution{Result: "error", ErrorCode: "INTERNAL_ERROR", ActionId: "last"})
+
+	executions, failures := h.snapshot()
+	assert.Len(t, executions, historySize)
+	assert.Equal(t, "last", executions[0].ActionId, "latest first")
+	assert.Equal(t, "7", executions[historySize-1].ActionId, "oldest executions are dropped")
+	assert.Equal(t, 2, failures)
+
+	h.record("ring", scheduler.Execution{Result: "ok"})
+	_, failures = h.snapshot()
+	assert.Equal(t, 0, failures)
+}
+
+func TestExecutionHistory(t *testing.T) {
+	s := New()
+	fail := true
+	_, err := s.AddFuncWithName("@every 1h", "settlement", func(ctx context.Context) {
+		if fail {
+			errors.Internal("settlement failed", "SETTLEMENT_FAILED")
+		}
+	})
+	require.NoError(t, err)
+
+	s.create("settlement", s.jobs["settlement"], "trigger-id")()
+	s.create("settlement", s.jobs["settlement"])()
+	info := s.JobInfo("settlement")
+	require.Len(t, info.History, 2)
+	assert.Equal(t, 2, info.ConsecutiveFailures)
+	assert.Equal(t, "error", info.History[1].Result)
+	assert.Equal(t, "SETTLEMENT_FAILED", info.History[1].ErrorCode)
+	assert.Equal(t, "trigger-id", info.History[1].RefId)
+	assert.NotEmpty(t, info.History[1].ActionId)
+	assert.False(t, info.History[1].End.Before(info.History[1].Start))
+
+	fail = false
+	s.create("settlement", s.jobs["settlement"])()
+	info = s.JobsInfo()[0]
+	assert.Equal(t, 0, info.ConsecutiveFailures)
+	assert.Equal(t, "ok", info.History[0].Result)
+	assert.Empty(t, info.History[0].ErrorCode)
+}
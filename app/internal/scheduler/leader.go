@@ -0,0 +1,121 @@
+package internal_scheduler
+
+import (
+	"context"
+	"fmt"
+	"github.com/odycenter/std-library/app/scheduler"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	leaseRenewPeriod   = 3 * time.Second                       // crashed leader is replaced within one period
+	leaseRenewInterval = leaseRenewPeriod / 3                  // leader renews and followers try to acquire every interval
+	leaseRenewTimeout  = leaseRenewInterval / 2                // renewal is given up before lease expires, leader steps down on failure
+	leaseTTL           = leaseRenewPeriod - leaseRenewInterval // lease expires after failed renewal, followers acquire it on next try
+)
+
+// leaderElection renews lease every interval, leader steps down if renewal is not done within timeout, which is before lease expires.
+// followers try to acquire at same interval, so new leader takes over within ttl plus interval after leader crashed,
+// or within one interval after lease is released
+type leaderElection struct {
+	lease     scheduler.Lease
+	owner     string
+	ttl       time.Duration
+	interval  time.Duration
+	timeout   time.Duration
+	leader    atomic.Value // string
+	isLeader  atomic.Bool
+	started   atomic.Bool
+	onElected func() // called when node becomes leader
+	stop      chan struct{}
+	done      chan struct{}
+	stopOnce  sync.Once
+}
+
+func newLeaderElection(lease scheduler.Lease) *leaderElection {
+	hostname, _ := os.Hostname()
+	e := &leaderElection{
+		lease:    lease,
+		owner:    fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		ttl:      leaseTTL,
+		interval: leaseRenewInterval,
+		timeout:  leaseRenewTimeout,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	e.leader.Store("")
+	return e
+}
+
+func (e *leaderElection) start() {
+	if e.started.Swap(true) {
+		return
+	}
+	e.elect()
+	go func() {
+		defer close(e.done)
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-e.stop:
+				return
+			case <-ticker.C:
+				e.elect()
+			}
+		}
+	}()
+}
+
+func (e *leaderElection) elect() {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+	holder, err := e.lease.Acquire(ctx, e.owner, e.ttl)
+	if err != nil {
+		// step down, lease may expire before next renewal and other node takes over
+		if e.isLeader.Swap(false) {
+			slog.Warn(fmt.Sprintf("[SCHEDULER_LEADER] failed to renew scheduler lease, step down, owner=%s, error=%v", e.owner, err))
+		} else {
+			slog.Warn(fmt.Sprintf("failed to acquire scheduler lease, owner=%s, error=%v", e.owner, err))
+		}
+		e.leader.Store("")
+		return
+	}
+	e.leader.Store(holder)
+	isLeader := holder == e.owner
+	if e.isLeader.Swap(isLeader) != isLeader {
+		if isLeader {
+			slog.Info(fmt.Sprintf("[SCHEDULER_LEADER] became scheduler leader, owner=%s", e.owner))
+			if e.onElected != nil {
+				go e.onElected()
+			}
+		} else {
+			slog.Warn(fmt.Sprintf("[SCHEDULER_LEADER] lost scheduler leadership, owner=%s, leader=%s", e.owner, holder))
+		}
+	}
+}
+
+// close stops renewal and releases lease if held, so other node takes over without waiting for expiration
+func (e *leaderElection) close(ctx context.Context) {
+	if !e.started.Load() {
+		return
+	}
+	e.stopOnce.Do(func() {
+		close(e.stop)
+		<-e.done
+		if e.isLeader.Swap(false) {
+			if err := e.lease.Release(ctx, e.owner); err != nil {
+				slog.WarnContext(ctx, fmt.Sprintf("failed to release scheduler lease, owner=%s, error=%v", e.owner, err))
+			}
+		}
+		e.leader.Store("")
+	})
+}
+
+func (e *leaderElection) currentLeader() string {
+	return e.leader.Load().(string)
+}
@@ -0,0 +1,100 @@
+package internal_scheduler
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type memoryLease struct {
+	mu        sync.Mutex
+	holder    string
+	expiredAt time.Time
+}
+
+func (l *memoryLease) Acquire(_ context.Context, owner string, ttl time.Duration) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.holder == "" || l.holder == owner || time.Now().After(l.expiredAt) {
+		l.holder = owner
+		l.expiredAt = time.Now().Add(ttl)
+	}
+	return l.holder, nil
+}
+
+func (l *memoryLease) Release(_ context.Context, owner string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.holder == owner {
+		l.holder = ""
+	}
+	return nil
+}
+
+func clusterScheduler(lease *memoryLease, owner string) *SchedulerImpl {
+	s := New()
+	s.Cluster(lease)
+	s.election.owner = owner
+	s.election.interval = 10 * time.Millisecond
+	return s
+}
+
+func TestOnlyLeaderFiresJobs(t *testing.T) {
+	lease := &memoryLease{}
+	leader := clusterScheduler(lease, "node-1")
+	follower := clusterScheduler(lease, "node-2")
+	leader.election.start()
+	follower.election.start()
+	defer follower.election.close(context.Background())
+
+	var leaderRuns, followerRuns atomic.Int32
+	leader.create("job", func(ctx context.Context) { leaderRuns.Add(1) })()
+	follower.create("job", func(ctx context.Context) { followerRuns.Add(1) })()
+	assert.Equal(t, int32(1), leaderRuns.Load())
+	assert.Equal(t, int32(0), followerRuns.Load())
+	assert.Equal(t, "node-1", follower.Leader())
+
+	leader.election.close(context.Background())
+	assert.Eventually(t, func() bool { return follower.Leader() == "node-2" }, time.Second, 5*time.Millisecond, "follower takes over after lease is released")
+	follower.create("job", func(ctx context.Context) { followerRuns.Add(1) })()
+	assert.Equal(t, int32(1), followerRuns.Load())
+}
+
+func TestFailoverAfterLeaseExpired(t *testing.T) {
+	lease := &memoryLease{}
+	leader := clusterScheduler(lease, "node-1")
+	leader.election.ttl = 30 * time.Millisecond
+	leader.election.elect() // elected once and crashed without release
+	follower := clusterScheduler(lease, "node-2")
+	follower.election.start()
+	defer follower.election.close(context.Background())
+
+	assert.False(t, follower.isLeader())
+	assert.Eventually(t, follower.isLeader, time.Second, 5*time.Millisecond)
+}
+
+func TestTriggerNowOnFollower(t *testing.T) {
+	lease := &memoryLease{holder: "node-1", expiredAt: time.Now().Add(time.Hour)}
+	follower := clusterScheduler(lease, "node-2")
+	follower.election.elect()
+	assert.False(t, follower.isLeader())
+
+	done := make(chan struct{})
+	_, err := follower.AddFuncWithName("@every 1h", "job", func(ctx context.Context) { close(done) })
+	assert.NoError(t, err)
+	follower.TriggerNow("job", "trigger-id")
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("job is not triggered")
+	}
+	assert.Equal(t, "node-1", follower.JobsInfo()[0].Leader)
+}
+
+func TestLeaseTiming(t *testing.T) {
+	assert.Less(t, leaseRenewInterval+leaseRenewTimeout, leaseTTL, "leader steps down before lease expires")
+	assert.LessOrEqual(t, leaseTTL+leaseRenewInterval, leaseRenewPeriod, "failover within one renewal period")
+}
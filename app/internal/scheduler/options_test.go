@@ -0,0 +1,147 @@
+package internal_scheduler
+
+import (
+	"context"
+	"github.com/odycenter/std-library/app/scheduler"
+	"github.com/odycenter/std-library/app/web/errors"
+	"github.com/robfig/cron/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	s := New()
+	var deadline time.Time
+	_, err := s.AddFuncWithOptions("@every 1h", "timeout", func(ctx context.Context) {
+		deadline, _ = ctx.Deadline()
+	}, scheduler.Options{Timeout: time.Minute})
+	require.NoError(t, err)
+
+	s.create("timeout", s.jobs["timeout"])()
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+}
+
+func TestRetries(t *testing.T) {
+	s := New()
+	var attempts atomic.Int32
+	_, err := s.AddFuncWithOptions("@every 1h", "retry", func(ctx context.Context) {
+		if attempts.Add(1) < 3 {
+			errors.Internal("failed", "RETRYABLE")
+		}
+	}, scheduler.Options{Retries: 3, RetryBackoff: time.Millisecond})
+	require.NoError(t, err)
+
+	s.create("retry", s.jobs["retry"])()
+	assert.Equal(t, int32(3), attempts.Load())
+	info := s.JobInfo("retry")
+	require.Len(t, info.History, 3)
+	assert.Equal(t, "ok", info.History[0].Result)
+	assert.Equal(t, 0, info.ConsecutiveFailures)
+}
+
+func TestRetriesExhausted(t *testing.T) {
+	s := New()
+	var attempts atomic.Int32
+	_, err := s.AddFuncWithOptions("@every 1h", "retry", func(ctx context.Context) {
+		attempts.Add(1)
+		errors.Internal("failed", "ALWAYS_FAILED")
+	}, scheduler.Options{Retries: 2, RetryBackoff: time.Millisecond})
+	require.NoError(t, err)
+
+	s.create("retry", s.jobs["retry"])()
+	assert.Equal(t, int32(3), attempts.Load())
+	assert.Equal(t, 3, s.JobInfo("retry").ConsecutiveFailures)
+}
+
+func TestDisallowConcurrentMisfire(t *testing.T) {
+	tests := []struct {
+		misfire scheduler.MisfirePolicy
+		runs    int32
+	}{
+		{scheduler.MisfireSkip, 1},
+		{scheduler.MisfireFireOnce, 2},
+		{scheduler.MisfireFireAll, 4},
+	}
+	for _, test := range tests {
+		t.Run(test.misfire.String(), func(t *testing.T) {
+			s := New()
+			var runs atomic.Int32
+			release := make(chan struct{})
+			_, err := s.AddFuncWithOptions("@every 1h", "job", func(ctx context.Context) {
+				if runs.Add(1) == 1 {
+					<-release
+				}
+			}, scheduler.Options{DisallowConcurrent: true, Misfire: test.misfire})
+			require.NoError(t, err)
+
+			p := s.create("job", s.jobs["job"])
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				p()
+			}()
+			assert.Eventually(t, func() bool { return runs.Load() == 1 }, time.Second, time.Millisecond)
+			p()
+			p()
+			p()
+			close(release)
+			wg.Wait()
+			assert.Equal(t, test.runs, runs.Load())
+		})
+	}
+}
+
+type memoryFireTimeStore struct {
+	mu    sync.Mutex
+	times map[string]time.Time
+}
+
+func (s *memoryFireTimeStore) LastFireTime(_ context.Context, job string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.times[job], nil
+}
+
+func (s *memoryFireTimeStore) Fired(_ context.Context, job string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.times[job] = at
+	return nil
+}
+
+func TestRecoverMisfires(t *testing.T) {
+	s := New()
+	store := &memoryFireTimeStore{times: map[string]time.Time{
+		"once":  time.Now().Add(-5 * time.Minute),
+		"all":   time.Now().Add(-5*time.Minute - time.Second),
+		"skip":  time.Now().Add(-5 * time.Minute),
+		"fresh": time.Now(),
+	}}
+	s.FireTimeStore(store)
+	runs := map[string]*atomic.Int32{}
+	for name, misfire := range map[string]scheduler.MisfirePolicy{"once": scheduler.MisfireFireOnce, "all": scheduler.MisfireFireAll, "skip": scheduler.MisfireSkip, "fresh": scheduler.MisfireFireAll} {
+		count := &atomic.Int32{}
+		runs[name] = count
+		_, err := s.AddFuncWithOptions("@every 1m", name, func(ctx context.Context) { count.Add(1) }, scheduler.Options{Misfire: misfire})
+		require.NoError(t, err)
+	}
+
+	s.recoverMisfires()
+	assert.Eventually(t, func() bool { return runs["once"].Load() == 1 && runs["all"].Load() == 5 }, time.Second, time.Millisecond)
+	assert.Equal(t, int32(0), runs["skip"].Load())
+	assert.Equal(t, int32(0), runs["fresh"].Load())
+	assert.WithinDuration(t, time.Now(), store.times["once"], time.Second, "fire time is saved")
+}
+
+func TestMissedFireTimes(t *testing.T) {
+	schedule := cron.Every(time.Minute)
+	now := time.Now()
+	assert.Equal(t, 0, missedFireTimes(schedule, now.Add(-30*time.Second), now))
+	assert.Equal(t, 3, missedFireTimes(schedule, now.Add(-3*time.Minute-time.Second), now))
+	assert.Equal(t, maxMisfires, missedFireTimes(schedule, now.Add(-24*time.Hour), now))
+}
@@ -0,0 +1,157 @@
+package internal_scheduler
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDisableEnable(t *testing.T) {
+	s := New()
+	id, err := s.AddFuncWithName("@every 1h", "job", func(ctx context.Context) {})
+	require.NoError(t, err)
+
+	s.Disable("job")
+	info := s.JobInfo("job")
+	assert.True(t, info.Disabled)
+	assert.Equal(t, id, info.ID, "job id is kept")
+	assert.True(t, info.Next.IsZero())
+	assert.Empty(t, s.cron.Entries())
+
+	s.Enable("job")
+	info = s.JobInfo("job")
+	assert.False(t, info.Disabled)
+	assert.Equal(t, id, info.ID)
+	assert.Len(t, s.cron.Entries(), 1)
+
+	assert.Panics(t, func() { s.Disable("not-exist") })
+}
+
+func TestUpdateSpec(t *testing.T) {
+	s := New()
+	id, err := s.AddFuncWithName("@every 1h", "job", func(ctx context.Context) {})
+	require.NoError(t, err)
+
+	require.NoError(t, s.UpdateSpec("job", "0 0 3 * * *"))
+	info := s.JobInfo("job")
+	assert.Equal(t, "0 0 3 * * *", info.Trigger)
+	assert.Equal(t, id, info.ID)
+	assert.Len(t, s.cron.Entries(), 1)
+
+	assert.Error(t, s.UpdateSpec("job", "invalid"))
+	assert.Equal(t, "0 0 3 * * *", s.JobInfo("job").Trigger, "invalid spec is not applied")
+
+	s.Disable("job")
+	require.NoError(t, s.UpdateSpec("job", "@every 2h"))
+	assert.Error(t, s.UpdateSpec("job", "invalid"))
+	s.Enable("job")
+	assert.Equal(t, "@every 2h", s.JobInfo("job").Trigger)
+	assert.Len(t, s.cron.Entries(), 1)
+}
+
+func TestRemove(t *testing.T) {
+	s := New()
+	id, err := s.AddFuncWithName("@every 1h", "job", func(ctx context.Context) {})
+	require.NoError(t, err)
+	_, err = s.AddFuncWithName("@every 1h", "job", func(ctx context.Context) {})
+	assert.ErrorContains(t, err, "job already exists")
+
+	s.Remove(id)
+	assert.Empty(t, s.JobsInfo())
+	assert.Empty(t, s.cron.Entries())
+	_, ok := s.histories.Load("job")
+	assert.False(t, ok)
+
+	_, err = s.AddFuncWithName("@every 1h", "job", func(ctx context.Context) {})
+	assert.NoError(t, err, "removed job can be registered again")
+}
+
+func TestRemoveDisabled(t *testing.T) {
+	s := New()
+	s.Overrides(func(name string) (string, bool) { return "", name == "disabled" })
+	disabled, err := s.AddFuncWithName("@every 1h", "disabled", func(ctx context.Context) {})
+	require.NoError(t, err)
+	assert.NotZero(t, disabled)
+	id, err := s.AddFuncWithName("@every 1h", "job", func(ctx context.Context) {})
+	require.NoError(t, err)
+	s.Disable("job")
+
+	s.Remove(disabled)
+	s.Remove(id)
+	assert.Empty(t, s.JobsInfo())
+	assert.Empty(t, s.jobs)
+	assert.Empty(t, s.options)
+	assert.Empty(t, s.cron.Entries())
+}
+
+func TestOverrides(t *testing.T) {
+	s := New()
+	s.Overrides(func(name string) (string, bool) {
+		if name == "disabled" {
+			return "", true
+		}
+		return "@every 2h", false
+	})
+	_, err := s.AddFuncWithName("@every 1h", "job", func(ctx context.Context) {})
+	require.NoError(t, err)
+	_, err = s.AddFuncWithName("@every 1h", "disabled", func(ctx context.Context) {})
+	require.NoError(t, err)
+
+	assert.Equal(t, "@every 2h", s.JobInfo("job").Trigger)
+	assert.True(t, s.JobInfo("disabled").Disabled)
+	assert.Len(t, s.cron.Entries(), 1)
+}
+
+func TestConcurrentTriggerAndModify(t *testing.T) {
+	s := New()
+	var runs atomic.Int32
+	process := func(ctx context.Context) { runs.Add(1) }
+	_, err := s.AddFuncWithName("* * * * * *", "job", process)
+	require.NoError(t, err)
+	s.Start()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				switch j % 5 {
+				case 0:
+					s.TriggerNow("job", "trigger-id")
+				case 1:
+					s.Disable("job")
+				case 2:
+					s.Enable("job")
+				case 3:
+					assert.NoError(t, s.UpdateSpec("job", fmt.Sprintf("*/%d * * * * *", j%3+1)))
+				case 4:
+					_ = s.JobsInfo()
+					_ = s.JobInfo("job")
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			name := fmt.Sprintf("dynamic-%d", i)
+			id, err := s.AddFuncWithName("@every 1h", name, process)
+			assert.NoError(t, err)
+			s.TriggerNow(name, "trigger-id")
+			s.Remove(id)
+		}
+	}()
+	wg.Wait()
+
+	s.AwaitTermination(context.Background(), 5000)
+	assert.Len(t, s.JobsInfo(), 1)
+	assert.LessOrEqual(t, len(s.cron.Entries()), 1, "no leaked cron entries")
+	assert.Eventually(t, func() bool { return runs.Load() >= 100 }, time.Second, time.Millisecond)
+}
@@ -0,0 +1,93 @@
+package internal_scheduler
+
+import (
+	"fmt"
+	"github.com/odycenter/std-library/app/scheduler"
+	"github.com/robfig/cron/v3"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	maxExcludedFireTimes = 1000 // stop searching if calendar excludes too many fire times, e.g. all days are excluded
+	nextFireTimes        = 5
+)
+
+var parser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// parse parses spec in time zone of options, and wraps schedule with calendar
+func parse(name, spec string, options scheduler.Options) (cron.Schedule, error) {
+	if options.TimeZone != "" {
+		if strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+			return nil, fmt.Errorf("time zone is set by both spec and options, name=%s, spec=%s, timeZone=%s", name, spec, options.TimeZone)
+		}
+		if _, err := time.LoadLocation(options.TimeZone); err != nil {
+			return nil, fmt.Errorf("invalid time zone, name=%s, timeZone=%s: %w", name, options.TimeZone, err)
+		}
+		spec = "CRON_TZ=" + options.TimeZone + " " + spec
+	}
+	schedule, err := parser.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	if options.Calendar == nil {
+		return schedule, nil
+	}
+	location := time.Local
+	if options.TimeZone != "" {
+		location, _ = time.LoadLocation(options.TimeZone)
+	}
+	return &calendarSchedule{name: name, schedule: schedule, calendar: options.Calendar, location: location}, nil
+}
+
+type calendarSchedule struct {
+	name     string
+	schedule cron.Schedule
+	calendar scheduler.Calendar
+	location *time.Location
+}
+
+// Next is called by cron to schedule job, excluded fire times are skipped and logged,
+// returns zero time if no fire time is found, then job is not fired
+func (c *calendarSchedule) Next(t time.Time) time.Time {
+	return c.next(t, true)
+}
+
+func (c *calendarSchedule) next(t time.Time, log bool) time.Time {
+	next := c.schedule.Next(t)
+	for i := 0; i < maxExcludedFireTimes && !next.IsZero(); i++ {
+		if !c.calendar.Excluded(next.In(c.location)) {
+			return next
+		}
+		if log {
+			slog.Info(fmt.Sprintf("skip fire time excluded by calendar, name: %s, fireTime: %v", c.name, next.In(c.location)))
+		}
+		next = c.schedule.Next(next)
+	}
+	if log && !next.IsZero() {
+		slog.Warn(fmt.Sprintf("no fire time found, all upcoming fire times are excluded by calendar, name: %s", c.name))
+	}
+	return time.Time{}
+}
+
+// next calculates next fire time without logging, for display and misfire detection
+func next(schedule cron.Schedule, t time.Time) time.Time {
+	if calendar, ok := schedule.(*calendarSchedule); ok {
+		return calendar.next(t, false)
+	}
+	return schedule.Next(t)
+}
+
+func upcoming(schedule cron.Schedule, from time.Time, count int) []time.Time {
+	if schedule == nil {
+		return nil
+	}
+	var result []time.Time
+	for t := next(schedule, from); !t.IsZero() && len(result) < count; t = next(schedule, t) {
+		result = append(result, t)
+	}
+	return result
+}
@@ -0,0 +1,63 @@
+package internal_scheduler
+
+import (
+	"context"
+	"github.com/odycenter/std-library/app/scheduler"
+	"github.com/odycenter/std-library/timex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseTimeZone(t *testing.T) {
+	shanghai, _ := time.LoadLocation(timex.CN)
+	schedule, err := parse("settlement", "0 5 0 * * *", scheduler.Options{TimeZone: timex.CN})
+	require.NoError(t, err)
+	next := schedule.Next(time.Date(2024, 9, 30, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, 10, 1, 0, 5, 0, 0, shanghai), next.In(shanghai))
+
+	_, err = parse("settlement", "0 5 0 * * *", scheduler.Options{TimeZone: "Mars/Olympus"})
+	assert.ErrorContains(t, err, "invalid time zone")
+	_, err = parse("settlement", "CRON_TZ=UTC 0 5 0 * * *", scheduler.Options{TimeZone: timex.CN})
+	assert.ErrorContains(t, err, "time zone is set by both spec and options")
+}
+
+func TestParseCalendar(t *testing.T) {
+	shanghai, _ := time.LoadLocation(timex.CN)
+	holidays := scheduler.Calendars(scheduler.NewDateCalendar("2024-10-01", "2024-10-02"), scheduler.NewWeeklyCalendar(time.Saturday, time.Sunday))
+	schedule, err := parse("settlement", "0 5 0 * * *", scheduler.Options{TimeZone: timex.CN, Calendar: holidays})
+	require.NoError(t, err)
+
+	next := schedule.Next(time.Date(2024, 9, 30, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, 10, 3, 0, 5, 0, 0, shanghai), next.In(shanghai), "holidays are skipped")
+	next = schedule.Next(next.Add(time.Hour * 24))
+	assert.Equal(t, time.Date(2024, 10, 7, 0, 5, 0, 0, shanghai), next.In(shanghai), "weekend is skipped")
+
+	schedule, err = parse("never", "0 5 0 * * *", scheduler.Options{Calendar: scheduler.NewWeeklyCalendar(0, 1, 2, 3, 4, 5, 6)})
+	require.NoError(t, err)
+	assert.True(t, schedule.Next(time.Now()).IsZero(), "no fire time if all days are excluded")
+}
+
+func TestNextFireTimes(t *testing.T) {
+	s := New()
+	today := time.Now().In(time.UTC).Format("2006-01-02")
+	tomorrow := time.Now().In(time.UTC).AddDate(0, 0, 1).Format("2006-01-02")
+	_, err := s.AddFuncWithOptions("0 0 12 * * *", "job", func(ctx context.Context) {}, scheduler.Options{
+		TimeZone: "UTC",
+		Calendar: scheduler.NewDateCalendar(today, tomorrow),
+	})
+	require.NoError(t, err)
+
+	info := s.JobInfo("job")
+	assert.Equal(t, "UTC", info.TimeZone)
+	require.Len(t, info.NextFireTimes, nextFireTimes)
+	for _, fireTime := range info.NextFireTimes {
+		date := fireTime.In(time.UTC).Format("2006-01-02")
+		assert.NotEqual(t, today, date)
+		assert.NotEqual(t, tomorrow, date)
+	}
+
+	require.NoError(t, s.UpdateSpec("job", "0 0 13 * * *"), "time zone and calendar are kept after spec change")
+	assert.Equal(t, 13, s.JobInfo("job").NextFireTimes[0].In(time.UTC).Hour())
+}
@@ -19,22 +19,39 @@ import (
 )
 
 type SchedulerImpl struct {
-	cron               *cron.Cron
-	panicOnAnyAddError bool
-	runningTaskCount   int32
-	runningTasks       sync.Map // map[action string]bool
-	disallowConcurrent sync.Map // map[action string]JobID
-	jobInfo            map[string]scheduler.JobInfo
-	jobs               map[string]func(ctx context.Context)
+	cron                *cron.Cron
+	panicOnAnyAddError  bool
+	runningTaskCount    int32
+	states              sync.Map     // map[action string]*jobState
+	histories           sync.Map     // map[action string]*history
+	mu                  sync.RWMutex // guards jobInfo, entries, jobs, options and lastID
+	jobInfo             map[string]scheduler.JobInfo
+	entries             map[string]cron.EntryID // cron entry of enabled job, job ID is kept when entry is changed
+	lastID              scheduler.JobID
+	jobs                map[string]func(ctx context.Context)
+	options             map[string]scheduler.Options
+	overrides           func(name string) (spec string, disabled bool)
+	election            *leaderElection
+	fireTimeStore       scheduler.FireTimeStore
+	delayedStore        scheduler.DelayedJobStore
+	delayedHandlers     map[string]func(ctx context.Context, payload string)
+	delayedRunning      sync.WaitGroup
+	delayedPollDone     chan struct{}
+	delayedClaimTimeout time.Duration
+	stopping            chan struct{}
+	stopOnce            sync.Once
 }
 
 func New() *SchedulerImpl {
 	s := &SchedulerImpl{}
-	s.cron = cron.New(cron.WithParser(cron.NewParser(
-		cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
-	)))
+	s.cron = cron.New(cron.WithParser(parser))
 	s.jobInfo = make(map[string]scheduler.JobInfo)
+	s.entries = make(map[string]cron.EntryID)
 	s.jobs = make(map[string]func(ctx context.Context))
+	s.options = make(map[string]scheduler.Options)
+	s.delayedHandlers = make(map[string]func(ctx context.Context, payload string))
+	s.delayedClaimTimeout = delayedClaimTimeout
+	s.stopping = make(chan struct{})
 	return s
 }
 
@@ -42,72 +59,199 @@ func (s *SchedulerImpl) PanicOnAnyAddError(val bool) {
 	s.panicOnAnyAddError = val
 }
 
+// Cluster enables leader election, only leader fires jobs by trigger, TriggerNow still runs on any node
+func (s *SchedulerImpl) Cluster(lease scheduler.Lease) {
+	s.election = newLeaderElection(lease)
+}
+
+// Leader returns owner of lease, empty if cluster mode is disabled or leader is unknown
+func (s *SchedulerImpl) Leader() string {
+	if s.election == nil {
+		return ""
+	}
+	return s.election.currentLeader()
+}
+
+// FireTimeStore persists fire times, so fire times missed during downtime are handled by misfire policy of job
+func (s *SchedulerImpl) FireTimeStore(store scheduler.FireTimeStore) {
+	s.fireTimeStore = store
+}
+
+// Overrides overrides spec or disables job on registration, e.g. by properties
+func (s *SchedulerImpl) Overrides(overrides func(name string) (spec string, disabled bool)) {
+	s.overrides = overrides
+}
+
+func (s *SchedulerImpl) isLeader() bool {
+	return s.election == nil || s.election.isLeader.Load()
+}
+
 func (s *SchedulerImpl) AddFunc(spec string, process func(ctx context.Context), panicOnAddError ...bool) (scheduler.JobID, error) {
 	action := reflects.FunctionName(process)
 	action = filepath.Base(action)
-	return s.add(spec, action, process, panicOnAddError...)
+	return s.add(spec, action, process, options(panicOnAddError))
 }
 
 func (s *SchedulerImpl) AddFuncWithName(spec, action string, process func(ctx context.Context), panicOnAddError ...bool) (scheduler.JobID, error) {
-	return s.add(spec, action, process, panicOnAddError...)
+	return s.add(spec, action, process, options(panicOnAddError))
+}
+
+func (s *SchedulerImpl) AddFuncWithOptions(spec, action string, process func(ctx context.Context), options scheduler.Options) (scheduler.JobID, error) {
+	return s.add(spec, action, process, options)
 }
 
 func (s *SchedulerImpl) Add(spec string, job scheduler.Job, panicOnAddError ...bool) (scheduler.JobID, error) {
 	action := reflects.StructName(job)
-	return s.add(spec, action, job.Execute, panicOnAddError...)
+	return s.add(spec, action, job.Execute, options(panicOnAddError))
 }
 
 func (s *SchedulerImpl) AddWithName(spec, action string, job scheduler.Job, panicOnAddError ...bool) (scheduler.JobID, error) {
-	return s.add(spec, action, job.Execute, panicOnAddError...)
+	return s.add(spec, action, job.Execute, options(panicOnAddError))
 }
 
-func (s *SchedulerImpl) AddDisallowConcurrentByName(spec, action string, job DisallowConcurrentJob, panicOnAddError ...bool) (scheduler.JobID, error) {
-	id, err := s.add(spec, action, job.job.Execute, panicOnAddError...)
-	if err == nil {
-		s.disallowConcurrent.Store(action, id)
-	}
-	return id, err
+func (s *SchedulerImpl) AddWithOptions(spec, action string, job scheduler.Job, options scheduler.Options) (scheduler.JobID, error) {
+	return s.add(spec, action, job.Execute, options)
+}
+
+func options(panicOnAddError []bool) scheduler.Options {
+	return scheduler.Options{PanicOnAddError: len(panicOnAddError) > 0 && panicOnAddError[0]}
 }
 
-func (s *SchedulerImpl) add(spec, action string, process func(ctx context.Context), panicOnAddError ...bool) (scheduler.JobID, error) {
+func (s *SchedulerImpl) add(spec, action string, process func(ctx context.Context), options scheduler.Options) (scheduler.JobID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	_, ok := s.jobInfo[action]
 	if ok {
-		panic("job already exists, name=" + action)
+		return 0, s.addError(fmt.Errorf("job already exists, name=%s", action), options)
 	}
-	p := s.create(action, process)
-	entryID, err := s.cron.AddFunc(spec, p)
-	if err != nil {
-		if s.panicOnAnyAddError || len(panicOnAddError) > 0 && panicOnAddError[0] {
-			panic(err)
+	disabled := false
+	if s.overrides != nil {
+		var overrideSpec string
+		overrideSpec, disabled = s.overrides(action)
+		if overrideSpec != "" {
+			slog.Info(fmt.Sprintf("override job spec, name: %v, spec: %v, override: %v", action, spec, overrideSpec))
+			spec = overrideSpec
 		}
-		return 0, err
 	}
 	info := scheduler.JobInfo{
-		ID:      scheduler.JobID(entryID),
-		Name:    action,
-		Trigger: spec,
+		Name:     action,
+		Trigger:  spec,
+		Disabled: disabled,
+		TimeZone: options.TimeZone,
+	}
+	schedule, err := parse(action, spec, options)
+	if err != nil {
+		return 0, s.addError(err, options)
 	}
+	s.lastID++
+	info.ID = s.lastID
+	if !disabled {
+		s.entries[action] = s.cron.Schedule(schedule, cron.FuncJob(s.create(action, process)))
+	}
+	s.options[action] = options
 	s.jobInfo[action] = info
 	s.jobs[action] = process
-	slog.Info(fmt.Sprintf("Job register successful, ID: %d, name: %v, spec: %v", entryID, action, spec))
-	return scheduler.JobID(entryID), nil
+	s.histories.Store(action, &history{})
+	slog.Info(fmt.Sprintf("Job register successful, ID: %d, name: %v, spec: %v, disabled: %v", info.ID, action, spec, disabled))
+	return info.ID, nil
+}
+
+func (s *SchedulerImpl) addError(err error, options scheduler.Options) error {
+	if s.panicOnAnyAddError || options.PanicOnAddError {
+		panic(err)
+	}
+	return err
 }
 
+// Remove unregisters job including disabled one, it can be registered again with same name
 func (s *SchedulerImpl) Remove(id scheduler.JobID) {
-	s.cron.Remove(cron.EntryID(id))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, info := range s.jobInfo {
+		if info.ID == id {
+			s.removeEntry(name)
+			delete(s.jobInfo, name)
+			delete(s.jobs, name)
+			delete(s.options, name)
+			s.histories.Delete(name)
+			s.states.Delete(name)
+			slog.Info(fmt.Sprintf("Job removed, ID: %d, name: %v", id, name))
+		}
+	}
+}
+
+// Disable stops firing job by trigger, it still can be triggered manually
+func (s *SchedulerImpl) Disable(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info := s.mustJobInfo(name)
+	if info.Disabled {
+		return
+	}
+	s.removeEntry(name)
+	info.Disabled = true
+	s.jobInfo[name] = info
+	slog.Warn(fmt.Sprintf("Job disabled, name: %v", name))
+}
+
+func (s *SchedulerImpl) Enable(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info := s.mustJobInfo(name)
+	if !info.Disabled {
+		return
+	}
+	schedule, err := parse(name, info.Trigger, s.options[name])
+	if err != nil {
+		errors.Internal(fmt.Sprintf("failed to enable job, name=%s, error=%v", name, err))
+	}
+	s.entries[name] = s.cron.Schedule(schedule, cron.FuncJob(s.create(name, s.jobs[name])))
+	info.Disabled = false
+	s.jobInfo[name] = info
+	slog.Warn(fmt.Sprintf("Job enabled, ID: %d, name: %v", info.ID, name))
+}
+
+// UpdateSpec changes spec of job, returns error if spec is invalid
+func (s *SchedulerImpl) UpdateSpec(name, spec string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info := s.mustJobInfo(name)
+	schedule, err := parse(name, spec, s.options[name])
+	if err != nil {
+		return err
+	}
+	if !info.Disabled {
+		entryID := s.cron.Schedule(schedule, cron.FuncJob(s.create(name, s.jobs[name])))
+		s.removeEntry(name)
+		s.entries[name] = entryID
+	}
+	slog.Warn(fmt.Sprintf("Job spec changed, ID: %d, name: %v, spec: %v, previous: %v", info.ID, name, spec, info.Trigger))
+	info.Trigger = spec
+	s.jobInfo[name] = info
+	return nil
+}
+
+// mustJobInfo must be called with lock held
+func (s *SchedulerImpl) mustJobInfo(name string) scheduler.JobInfo {
+	info, ok := s.jobInfo[name]
+	if !ok {
+		errors.NotFound("job not found, name=" + name)
+	}
+	return info
 }
 
-func (s *SchedulerImpl) info(id scheduler.JobID) scheduler.JobInfo {
-	e := s.cron.Entry(cron.EntryID(id))
-	return scheduler.JobInfo{
-		ID:   scheduler.JobID(e.ID),
-		Next: e.Next,
-		Prev: e.Prev,
+// removeEntry must be called with lock held
+func (s *SchedulerImpl) removeEntry(name string) {
+	if entryID, ok := s.entries[name]; ok {
+		s.cron.Remove(entryID)
+		delete(s.entries, name)
 	}
 }
 
 func (s *SchedulerImpl) TriggerNow(name string, triggerActionId string) {
+	s.mu.RLock()
 	process, ok := s.jobs[name]
+	s.mu.RUnlock()
 	if !ok {
 		errors.NotFound("job not found, name=" + name)
 	}
@@ -117,10 +261,16 @@ func (s *SchedulerImpl) TriggerNow(name string, triggerActionId string) {
 
 func (s *SchedulerImpl) JobsInfo() []scheduler.JobInfo {
 	var result []scheduler.JobInfo
+	leader := s.Leader()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	for _, entry := range s.jobInfo {
-		e := s.cron.Entry(cron.EntryID(entry.ID))
+		e := s.cron.Entry(s.entries[entry.Name])
 		entry.Next = e.Next
 		entry.Prev = e.Prev
+		entry.NextFireTimes = upcoming(e.Schedule, time.Now(), nextFireTimes)
+		entry.Leader = leader
+		entry.History, entry.ConsecutiveFailures = s.history(entry.Name)
 		result = append(result, entry)
 	}
 
@@ -131,11 +281,47 @@ func (s *SchedulerImpl) JobsInfo() []scheduler.JobInfo {
 	return result
 }
 
+// JobInfo returns info of job with recent executions
+func (s *SchedulerImpl) JobInfo(name string) scheduler.JobInfo {
+	s.mu.RLock()
+	info, ok := s.jobInfo[name]
+	entryID := s.entries[name]
+	s.mu.RUnlock()
+	if !ok {
+		errors.NotFound("job not found, name=" + name)
+	}
+	e := s.cron.Entry(entryID)
+	info.Next = e.Next
+	info.Prev = e.Prev
+	info.NextFireTimes = upcoming(e.Schedule, time.Now(), nextFireTimes)
+	info.Leader = s.Leader()
+	info.History, info.ConsecutiveFailures = s.history(name)
+	return info
+}
+
+func (s *SchedulerImpl) history(name string) ([]scheduler.Execution, int) {
+	h, ok := s.histories.Load(name)
+	if !ok {
+		return nil, 0
+	}
+	return h.(*history).snapshot()
+}
+
 func (s *SchedulerImpl) RunningTasks() int {
 	return int(atomic.LoadInt32(&s.runningTaskCount))
 }
 
 func (s *SchedulerImpl) Start() {
+	if s.election != nil {
+		s.election.onElected = s.recoverMisfires
+		s.election.start()
+	} else {
+		go s.recoverMisfires()
+	}
+	if s.delayedStore != nil {
+		s.delayedPollDone = make(chan struct{})
+		go s.pollDelayed()
+	}
 	s.cron.Start()
 }
 
@@ -145,6 +331,7 @@ func (s *SchedulerImpl) Execute(_ context.Context) {
 
 func (s *SchedulerImpl) AwaitTermination(ctx context.Context, timeoutInMs int64) {
 	slog.InfoContext(ctx, "shutting down scheduler")
+	s.stopOnce.Do(func() { close(s.stopping) })
 
 	innerCtx, cancel := context.WithTimeout(ctx, time.Duration(timeoutInMs)*time.Millisecond)
 	defer cancel()
@@ -152,47 +339,252 @@ func (s *SchedulerImpl) AwaitTermination(ctx context.Context, timeoutInMs int64)
 	select {
 	case <-innerCtx.Done():
 		slog.InfoContext(innerCtx, fmt.Sprintf("[FAILED_TO_STOP] failed to terminate scheduler, due to timeout, canceledTasks=%d", s.RunningTasks()))
-	case <-s.cron.Stop().Done():
+	case <-s.stopped():
 		slog.InfoContext(innerCtx, "all jobs have completed")
 	}
+	if s.election != nil {
+		s.election.close(ctx)
+	}
+}
+
+// stopped is done when cron jobs and delayed jobs are completed
+func (s *SchedulerImpl) stopped() <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		<-s.cron.Stop().Done()
+		if s.delayedPollDone != nil {
+			<-s.delayedPollDone
+		}
+		s.delayedRunning.Wait()
+		close(done)
+	}()
+	return done
 }
 
 func (s *SchedulerImpl) create(action string, process func(ctx context.Context), triggerActionId ...string) func() {
 	return func() {
+		if len(triggerActionId) == 0 && !s.isLeader() {
+			slog.Debug(fmt.Sprintf("skip job due to not scheduler leader, action: %s, leader: %s", action, s.Leader()))
+			return
+		}
+		if len(triggerActionId) == 0 {
+			s.fired(action)
+		}
 		atomic.AddInt32(&s.runningTaskCount, 1)
 		defer atomic.AddInt32(&s.runningTaskCount, -1)
 
-		id, ok := s.disallowConcurrent.Load(action)
-		if ok {
-			_, running := s.runningTasks.LoadOrStore(action, true)
-			if running {
-				info := s.info(id.(scheduler.JobID))
-				slog.Warn(fmt.Sprintf("reject job due to disallow Concurrent, %s(id:%v) is still running. previous fire:%v, next fire: %v", action, info.ID, info.Prev, info.Next))
+		s.mu.RLock()
+		options := s.options[action]
+		id := s.jobInfo[action].ID
+		entryID := s.entries[action]
+		s.mu.RUnlock()
+		if !options.DisallowConcurrent {
+			s.execute(action, process, options, triggerActionId...)
+			return
+		}
+
+		state, _ := s.states.LoadOrStore(action, &jobState{})
+		if !state.(*jobState).acquire(options.Misfire) {
+			e := s.cron.Entry(entryID)
+			slog.Warn(fmt.Sprintf("reject job due to disallow Concurrent, %s(id:%v) is still running. previous fire:%v, next fire: %v, misfire: %v", action, id, e.Prev, e.Next, options.Misfire))
+			return
+		}
+		for {
+			s.execute(action, process, options, triggerActionId...)
+			if !state.(*jobState).next() {
 				return
 			}
+			slog.Info(fmt.Sprintf("fire job rejected while previous run was running, action: %s, misfire: %v", action, options.Misfire))
+		}
+	}
+}
+
+// execute runs job with timeout, and retries with backoff on panic
+func (s *SchedulerImpl) execute(action string, process func(ctx context.Context), options scheduler.Options, triggerActionId ...string) {
+	if options.Timeout > 0 {
+		process = withTimeout(process, options.Timeout)
+	}
+	backoff := options.RetryBackoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	for attempt := 0; ; attempt++ {
+		p := process
+		if attempt > 0 {
+			p = withRetryAttempt(process, attempt)
+		}
+		execution, executed := create(action, p, triggerActionId...)
+		if !executed {
+			return
+		}
+		h, _ := s.histories.LoadOrStore(action, &history{})
+		h.(*history).record(action, *execution)
+		if execution.Result == "ok" || attempt >= options.Retries {
+			return
+		}
 
-			defer s.runningTasks.Delete(action)
+		delay := backoff << attempt
+		slog.Warn(fmt.Sprintf("retry job after %v, action: %s, attempt: %d/%d, error_code: %s", delay, action, attempt+1, options.Retries, execution.ErrorCode))
+		timer := time.NewTimer(delay)
+		select {
+		case <-s.stopping:
+			timer.Stop()
+			slog.Info(fmt.Sprintf("cancel job retry due to scheduler is shutting down, action: %s", action))
+			return
+		case <-timer.C:
 		}
+	}
+}
+
+func withTimeout(process func(ctx context.Context), timeout time.Duration) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		process(ctx)
+		if ctx.Err() == context.DeadlineExceeded {
+			actionlog.Context(&ctx, "timeout", true)
+			slog.WarnContext(ctx, fmt.Sprintf("job exceeded max execution time, timeout: %v", timeout))
+		}
+	}
+}
 
-		create(action, process, triggerActionId...)
+func withRetryAttempt(process func(ctx context.Context), attempt int) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		actionlog.Context(&ctx, "retry_attempt", attempt)
+		process(ctx)
 	}
 }
 
-func create(action string, process func(ctx context.Context), triggerActionId ...string) {
+func (s *SchedulerImpl) fired(action string) {
+	if s.fireTimeStore == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.fireTimeStore.Fired(ctx, action, time.Now()); err != nil {
+		slog.Warn(fmt.Sprintf("failed to save job fire time, action: %s, error: %v", action, err))
+	}
+}
+
+// recoverMisfires fires jobs missed since last fire time by misfire policy, it's called on start, or when node becomes leader in cluster mode
+func (s *SchedulerImpl) recoverMisfires() {
+	if s.fireTimeStore == nil {
+		return
+	}
+	type misfireJob struct {
+		action   string
+		policy   scheduler.MisfirePolicy
+		schedule cron.Schedule
+		process  func(ctx context.Context)
+	}
+	var jobs []misfireJob
+	s.mu.RLock()
+	for action, options := range s.options {
+		info := s.jobInfo[action]
+		if options.Misfire != scheduler.MisfireSkip && !info.Disabled {
+			jobs = append(jobs, misfireJob{action: action, policy: options.Misfire, schedule: s.cron.Entry(s.entries[action]).Schedule, process: s.jobs[action]})
+		}
+	}
+	s.mu.RUnlock()
+
+	now := time.Now()
+	for _, job := range jobs {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		last, err := s.fireTimeStore.LastFireTime(ctx, job.action)
+		cancel()
+		if err != nil {
+			slog.Warn(fmt.Sprintf("failed to load job fire time, action: %s, error: %v", job.action, err))
+			continue
+		}
+		if last.IsZero() {
+			continue
+		}
+		missed := missedFireTimes(job.schedule, last, now)
+		if missed == 0 {
+			continue
+		}
+		slog.Warn(fmt.Sprintf("[JOB_MISFIRED] job missed fire times, action: %s, last fire: %v, missed: %d, misfire: %v", job.action, last, missed, job.policy))
+		if job.policy == scheduler.MisfireFireOnce {
+			missed = 1
+		}
+		p := s.create(job.action, job.process)
+		go func() {
+			for i := 0; i < missed; i++ {
+				p()
+			}
+		}()
+	}
+}
+
+const maxMisfires = 100
+
+func missedFireTimes(schedule cron.Schedule, last, now time.Time) int {
+	if schedule == nil {
+		return 0
+	}
+	count := 0
+	for t := next(schedule, last); !t.IsZero() && !t.After(now) && count < maxMisfires; t = next(schedule, t) {
+		count++
+	}
+	return count
+}
+
+// jobState tracks running job which disallows concurrent, rejected triggers are pending by misfire policy
+type jobState struct {
+	mu      sync.Mutex
+	running bool
+	pending int
+}
+
+func (j *jobState) acquire(policy scheduler.MisfirePolicy) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if !j.running {
+		j.running = true
+		return true
+	}
+	switch policy {
+	case scheduler.MisfireFireOnce:
+		j.pending = 1
+	case scheduler.MisfireFireAll:
+		j.pending++
+	}
+	return false
+}
+
+// next returns true if there is pending trigger, otherwise releases job
+func (j *jobState) next() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.pending > 0 {
+		j.pending--
+		return true
+	}
+	j.running = false
+	return false
+}
+
+func create(action string, process func(ctx context.Context), triggerActionId ...string) (execution *scheduler.Execution, executed bool) {
 	actionName := "job:" + action
 	if internal.IsShutdown() {
 		slog.Info(fmt.Sprintf("reject job due to server is shutting down!! action: %s", actionName))
-		return
+		return nil, false
 	}
 	actionLog := actionlog.Begin(actionName, "job")
 	if triggerActionId != nil && len(triggerActionId) > 0 {
 		actionLog.RefId = triggerActionId[0]
 	}
+	execution = &scheduler.Execution{Start: actionLog.Timestamp, Result: "ok", ActionId: actionLog.Id, RefId: actionLog.RefId}
+	executed = true
 
 	contextMap := make(map[string][]any)
 	statMap := make(map[string]float64)
 	defer func() {
+		execution.End = time.Now()
+		execution.Duration = execution.End.Sub(execution.Start)
 		if err := recover(); err != nil {
+			execution.Result = "error"
+			execution.ErrorCode = errorCode(err)
 			actionLog.AddStat(statMap)
 
 			actionlog.HandleRecover(err, actionLog, contextMap)
@@ -207,4 +599,12 @@ func create(action string, process func(ctx context.Context), triggerActionId ..
 	actionLog.AddContext(contextMap)
 	actionLog.AddStat(statMap)
 	actionlog.End(actionLog, "ok")
+	return execution, executed
+}
+
+func errorCode(err any) string {
+	if code, ok := err.(errors.Code); ok {
+		return code.ErrorCode()
+	}
+	return "INTERNAL_ERROR"
 }
@@ -1,24 +1,32 @@
 package internal_sys
 
 import (
+	"fmt"
 	internalcache "github.com/odycenter/std-library/app/internal/cache"
 	"github.com/odycenter/std-library/app/internal/web/http"
 	"github.com/odycenter/std-library/app/web/errors"
 	"github.com/odycenter/std-library/json"
 	"github.com/odycenter/std-library/nets"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
+const (
+	defaultKeyCount = 100
+	maxKeyCount     = 1000
+)
+
 type CacheController struct {
 	caches        map[string]*internalcache.CacheImpl
 	accessControl *internal_http.IPv4AccessControl
 }
 
-func NewCacheController(caches map[string]*internalcache.CacheImpl) *CacheController {
+func NewCacheController(caches map[string]*internalcache.CacheImpl, accessControl *internal_http.IPv4AccessControl) *CacheController {
 	return &CacheController{
 		caches:        caches,
-		accessControl: &internal_http.IPv4AccessControl{},
+		accessControl: accessControl,
 	}
 }
 
@@ -41,8 +49,9 @@ func (c *CacheController) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	path := strings.TrimPrefix(r.URL.Path, "/_sys/cache/")
 	parts := strings.SplitN(path, "/", 2)
-	if len(parts) != 2 {
-		errors.NotFound("not found")
+	if len(parts) == 1 {
+		c.serveNamespace(w, r, parts[0])
+		return
 	}
 
 	name := parts[0]
@@ -62,6 +71,10 @@ func (c *CacheController) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if !ok {
 			errors.NotFoundError(404, "cache key not found, name="+name+", key="+key)
 		}
+		if info, ok := cache.KeyInfo(r.Context(), key); ok {
+			w.Header().Set("X-Cache-TTL", info.TTL.String())
+			w.Header().Set("X-Cache-Size", strconv.FormatInt(info.Size, 10))
+		}
 		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
 		w.Header().Set("Pragma", "no-cache")
 		w.Header().Set("Expires", "0")
@@ -73,6 +86,51 @@ func (c *CacheController) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	errors.NotFound("not found")
 }
 
+// serveNamespace lists keys by GET /_sys/cache/{name}?pattern=*&cursor=0&count=100, and evicts all keys by DELETE /_sys/cache/{name}
+func (c *CacheController) serveNamespace(w http.ResponseWriter, r *http.Request, name string) {
+	cache := c.cache(name)
+	if r.Method == http.MethodDelete {
+		slog.WarnContext(r.Context(), fmt.Sprintf("[MANUAL_OPERATION] evict cache namespace, name=%s", name))
+		evicted, err := cache.EvictNamespace(r.Context())
+		if err != nil {
+			errors.BadRequest(err.Error(), "CACHE_EVICT_FAILED")
+		}
+		w.WriteHeader(200)
+		w.Write([]byte("cache namespace evicted, name=" + name + ", evicted=" + strconv.Itoa(evicted)))
+		return
+	}
+	if r.Method != http.MethodGet {
+		errors.NotFound("not found")
+	}
+
+	query := r.URL.Query()
+	cursor, err := strconv.ParseUint(query.Get("cursor"), 10, 64)
+	if err != nil && query.Get("cursor") != "" {
+		errors.BadRequest("invalid cursor, cursor="+query.Get("cursor"), "INVALID_CURSOR")
+	}
+	count := int64(defaultKeyCount)
+	if value := query.Get("count"); value != "" {
+		count, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || count <= 0 || count > maxKeyCount {
+			errors.BadRequest(fmt.Sprintf("count must be between 1 and %d, count=%s", maxKeyCount, value), "INVALID_COUNT")
+		}
+	}
+	keys, next, err := cache.Keys(r.Context(), query.Get("pattern"), cursor, count)
+	if err != nil {
+		errors.BadRequest(err.Error(), "CACHE_SCAN_FAILED")
+	}
+	view := CacheKeysView{Name: name, Cursor: strconv.FormatUint(next, 10), Keys: make([]CacheKeyView, 0, len(keys))}
+	for _, key := range keys {
+		view.Keys = append(view.Keys, CacheKeyView{Key: key.Key, TTL: key.TTL.String(), Size: key.Size})
+	}
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Set("Pragma", "no-cache")
+	w.Header().Set("Expires", "0")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(json.Stringify(view))
+}
+
 func (c *CacheController) list() []CacheView {
 	var views []CacheView
 	for _, cache := range c.caches {
@@ -90,15 +148,57 @@ func (c *CacheController) cache(name string) *internalcache.CacheImpl {
 }
 
 func view(cache *internalcache.CacheImpl) CacheView {
-	return CacheView{
+	view := CacheView{
 		Name:     cache.Name(),
 		Type:     cache.GetTypeName(),
 		Duration: cache.Expiration.String(),
+		Store:    store(cache.CacheStore),
+		Policy:   "expire",
+	}
+	if cache.Options.SoftExpiration > 0 {
+		view.Policy = "stale-while-revalidate"
+		view.SoftDuration = cache.Options.SoftExpiration.String()
+	}
+	if cache.Options.NotFoundExpiration > 0 {
+		view.NotFoundDuration = cache.Options.NotFoundExpiration.String()
+		view.TombstonesCreated = cache.TombstonesCreated()
+		view.TombstoneHits = cache.TombstoneHits()
 	}
+	return view
+}
+
+func store(cacheStore internalcache.CacheStore) string {
+	switch cacheStore.(type) {
+	case *internalcache.TieredCacheStore:
+		return "local+redis"
+	case *internalcache.RedisCacheStore:
+		return "redis"
+	case *internalcache.LocalCacheStore:
+		return "local"
+	}
+	return ""
 }
 
 type CacheView struct {
-	Name     string
-	Type     string
-	Duration string
+	Name              string
+	Type              string
+	Duration          string
+	SoftDuration      string
+	NotFoundDuration  string
+	Policy            string
+	Store             string
+	TombstonesCreated int64
+	TombstoneHits     int64
+}
+
+type CacheKeysView struct {
+	Name   string
+	Cursor string // next cursor, "0" means iteration is completed
+	Keys   []CacheKeyView
+}
+
+type CacheKeyView struct {
+	Key  string
+	TTL  string
+	Size int64
 }
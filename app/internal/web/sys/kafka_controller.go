@@ -0,0 +1,170 @@
+package internal_sys
+
+import (
+	"fmt"
+	"github.com/odycenter/std-library/app/internal/web/http"
+	"github.com/odycenter/std-library/app/kafka"
+	actionlog "github.com/odycenter/std-library/app/log"
+	"github.com/odycenter/std-library/app/web/errors"
+	"github.com/odycenter/std-library/json"
+	"github.com/odycenter/std-library/nets"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KafkaController is shared by all kafka configs,
+// GET /_sys/kafka lists listeners, POST /_sys/kafka/{topic}/pause|resume, PUT /_sys/kafka/{topic}/offset?to=earliest|latest|RFC3339 time,
+// use ?group= if topic is subscribed by multiple groups
+type KafkaController struct {
+	accessControl *internal_http.IPv4AccessControl
+	listeners     []*kafka.MessageListener
+	mu            sync.RWMutex
+}
+
+func NewKafkaController(accessControl *internal_http.IPv4AccessControl) *KafkaController {
+	return &KafkaController{
+		accessControl: accessControl,
+	}
+}
+
+func (c *KafkaController) Add(listener *kafka.MessageListener) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, listener)
+}
+
+func (c *KafkaController) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	err := c.accessControl.Validate(nets.IP(r).String())
+	if err != nil {
+		errors.Forbidden("access denied", "IP_ACCESS_DENIED")
+	}
+
+	if r.Method == http.MethodGet && r.URL.Path == "/_sys/kafka" {
+		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		w.Header().Set("Pragma", "no-cache")
+		w.Header().Set("Expires", "0")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(json.Stringify(c.list()))
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/_sys/kafka/")
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) != 2 {
+		errors.NotFound("not found")
+	}
+	topic, operation := parts[0], parts[1]
+	listener := c.listener(topic, r.URL.Query().Get("group"))
+	ctx := r.Context()
+	actionlog.Context(&ctx, "manual_operation", true)
+
+	switch {
+	case r.Method == http.MethodPost && operation == "pause":
+		slog.WarnContext(ctx, fmt.Sprintf("[MANUAL_OPERATION] pause kafka listener, topic=%s, group=%s", topic, listener.Opt.GroupId))
+		listener.Pause()
+		w.WriteHeader(200)
+		w.Write([]byte("listener paused, topic=" + topic))
+	case r.Method == http.MethodPost && operation == "resume":
+		slog.WarnContext(ctx, fmt.Sprintf("[MANUAL_OPERATION] resume kafka listener, topic=%s, group=%s", topic, listener.Opt.GroupId))
+		listener.Resume()
+		w.WriteHeader(200)
+		w.Write([]byte("listener resumed, topic=" + topic))
+	case r.Method == http.MethodPut && operation == "offset":
+		to := r.URL.Query().Get("to")
+		slog.WarnContext(ctx, fmt.Sprintf("[MANUAL_OPERATION] reset kafka offset, topic=%s, group=%s, to=%s", topic, listener.Opt.GroupId, to))
+		var offsets map[int]int64
+		switch to {
+		case "earliest":
+			offsets, err = listener.ResetOffset(ctx, kafka.FirstOffset)
+		case "latest":
+			offsets, err = listener.ResetOffset(ctx, kafka.LastOffset)
+		default:
+			at, parseErr := time.Parse(time.RFC3339, to)
+			if parseErr != nil {
+				errors.BadRequest("to must be earliest, latest or RFC3339 time, to="+to, "INVALID_OFFSET")
+			}
+			offsets, err = listener.ResetOffsetByTime(ctx, at)
+		}
+		if err != nil {
+			errors.Internal(err.Error(), "RESET_OFFSET_FAILED")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write(json.Stringify(offsets))
+	default:
+		errors.NotFound("not found")
+	}
+}
+
+func (c *KafkaController) list() []KafkaListenerView {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	views := make([]KafkaListenerView, 0, len(c.listeners))
+	for _, listener := range c.listeners {
+		view := KafkaListenerView{
+			Topic:        listener.Topic(),
+			Group:        listener.Opt.GroupId,
+			PoolSize:     listener.PoolSize(),
+			RunningTasks: listener.RunningTasks(),
+			Paused:       listener.Paused(),
+		}
+		for _, stats := range listener.ReaderStats() {
+			view.Readers = append(view.Readers, KafkaReaderView{
+				ClientId:    stats.ClientID,
+				Topic:       stats.Topic,
+				Partition:   stats.Partition,
+				Offset:      stats.Offset,
+				Lag:         stats.Lag,
+				Messages:    stats.Messages,
+				Errors:      stats.Errors,
+				Rebalances:  stats.Rebalances,
+				QueueLength: stats.QueueLength,
+			})
+		}
+		views = append(views, view)
+	}
+	return views
+}
+
+func (c *KafkaController) listener(topic, group string) *kafka.MessageListener {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var matched []*kafka.MessageListener
+	for _, listener := range c.listeners {
+		if listener.Topic() == topic && (group == "" || listener.Opt.GroupId == group) {
+			matched = append(matched, listener)
+		}
+	}
+	if len(matched) == 0 {
+		errors.NotFoundError(404, "kafka listener not found, topic="+topic)
+	}
+	if len(matched) > 1 {
+		errors.BadRequest("topic is subscribed by multiple groups, please specify group, topic="+topic, "GROUP_REQUIRED")
+	}
+	return matched[0]
+}
+
+type KafkaListenerView struct {
+	Topic        string
+	Group        string
+	PoolSize     int
+	RunningTasks int
+	Paused       bool
+	Readers      []KafkaReaderView // counters are since last request
+}
+
+type KafkaReaderView struct {
+	ClientId    string
+	Topic       string
+	Partition   string
+	Offset      int64
+	Lag         int64
+	Messages    int64
+	Errors      int64
+	Rebalances  int64
+	QueueLength int64
+}
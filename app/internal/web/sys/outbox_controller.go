@@ -0,0 +1,71 @@
+package internal_sys
+
+import (
+	"github.com/odycenter/std-library/app/internal/web/http"
+	"github.com/odycenter/std-library/app/outbox"
+	"github.com/odycenter/std-library/app/web/errors"
+	"github.com/odycenter/std-library/json"
+	"github.com/odycenter/std-library/nets"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// OutboxController is shared by all outbox configs, GET /_sys/outbox shows backlog of each outbox
+type OutboxController struct {
+	accessControl *internal_http.IPv4AccessControl
+	relays        []*outbox.Relay
+	mu            sync.RWMutex
+}
+
+func NewOutboxController(accessControl *internal_http.IPv4AccessControl) *OutboxController {
+	return &OutboxController{
+		accessControl: accessControl,
+	}
+}
+
+func (c *OutboxController) Add(relay *outbox.Relay) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.relays = append(c.relays, relay)
+}
+
+func (c *OutboxController) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	err := c.accessControl.Validate(nets.IP(r).String())
+	if err != nil {
+		errors.Forbidden("access denied", "IP_ACCESS_DENIED")
+	}
+	if r.Method != http.MethodGet {
+		errors.MethodNotAllowed("method not allowed")
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	views := make([]OutboxView, 0, len(c.relays))
+	for _, relay := range c.relays {
+		view := OutboxView{Name: relay.Name}
+		backlog, err := relay.Store.Backlog(r.Context())
+		if err != nil {
+			view.Error = err.Error()
+		} else {
+			view.Pending = backlog.Pending
+			view.Retrying = backlog.Retrying
+			view.OldestCreatedAt = backlog.OldestCreatedAt
+		}
+		views = append(views, view)
+	}
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Set("Pragma", "no-cache")
+	w.Header().Set("Expires", "0")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(json.Stringify(views))
+}
+
+type OutboxView struct {
+	Name            string
+	Pending         int64
+	Retrying        int64
+	OldestCreatedAt *time.Time
+	Error           string `json:",omitempty"`
+}
@@ -11,6 +11,7 @@ import (
 	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 )
 
 type SchedulerController struct {
@@ -32,29 +33,116 @@ func (c *SchedulerController) ServeHTTP(w http.ResponseWriter, r *http.Request)
 	}
 
 	if r.Method == http.MethodGet && r.URL.Path == "/_sys/job" {
-		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-		w.Header().Set("Pragma", "no-cache")
-		w.Header().Set("Expires", "0")
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(200)
-		w.Write(json.Stringify(c.scheduler.JobsInfo()))
+		writeJSON(w, c.scheduler.JobsInfo())
+		return
+	}
+
+	if r.URL.Path == "/_sys/job/delayed" || strings.HasPrefix(r.URL.Path, "/_sys/job/delayed/") {
+		c.delayed(w, r)
 		return
 	}
 
 	path := strings.TrimPrefix(r.URL.Path, "/_sys/job/")
-	parts := strings.SplitN(path, "/", 1)
-	if len(parts) != 1 || r.Method != http.MethodPost {
+	parts := strings.Split(path, "/")
+	if len(parts) > 2 || parts[0] == "" {
 		errors.NotFound("not found")
 	}
 
 	job := parts[0]
 	ctx := r.Context()
-	slog.WarnContext(ctx, fmt.Sprintf("[MANUAL_OPERATION] trigger job manually, job=%s", job))
-	actionlog.Context(&ctx, "manual_operation", true)
-	id := actionlog.GetId(&ctx)
-	c.scheduler.TriggerNow(job, id)
-	w.WriteHeader(202)
-
-	w.Write([]byte("job triggered, job=" + job + ", id=" + id))
-	return
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 1:
+		writeJSON(w, c.scheduler.JobInfo(job))
+	case r.Method == http.MethodPost && len(parts) == 1:
+		slog.WarnContext(ctx, fmt.Sprintf("[MANUAL_OPERATION] trigger job manually, job=%s", job))
+		actionlog.Context(&ctx, "manual_operation", true)
+		id := actionlog.GetId(&ctx)
+		c.scheduler.TriggerNow(job, id)
+		w.WriteHeader(202)
+		w.Write([]byte("job triggered, job=" + job + ", id=" + id))
+	case r.Method == http.MethodPut && len(parts) == 2:
+		c.modify(w, r, job, parts[1])
+	default:
+		errors.NotFound("not found")
+	}
+}
+
+// modify changes job at runtime, changes are not persisted, set sys.job.{name}.spec or sys.job.{name}.enabled in properties to keep them after restart
+func (c *SchedulerController) modify(w http.ResponseWriter, r *http.Request, job, operation string) {
+	ctx := r.Context()
+	switch operation {
+	case "enable":
+		slog.WarnContext(ctx, fmt.Sprintf("[MANUAL_OPERATION] enable job, job=%s", job))
+		actionlog.Context(&ctx, "manual_operation", true)
+		c.scheduler.Enable(job)
+	case "disable":
+		slog.WarnContext(ctx, fmt.Sprintf("[MANUAL_OPERATION] disable job, job=%s", job))
+		actionlog.Context(&ctx, "manual_operation", true)
+		c.scheduler.Disable(job)
+	case "spec":
+		spec := r.URL.Query().Get("spec")
+		if spec == "" {
+			errors.BadRequest("spec is required", "INVALID_SPEC")
+		}
+		slog.WarnContext(ctx, fmt.Sprintf("[MANUAL_OPERATION] change job spec, job=%s, spec=%s", job, spec))
+		actionlog.Context(&ctx, "manual_operation", true)
+		if err := c.scheduler.UpdateSpec(job, spec); err != nil {
+			errors.BadRequest("invalid spec, error="+err.Error(), "INVALID_SPEC")
+		}
+	default:
+		errors.NotFound("not found")
+	}
+	writeJSON(w, c.scheduler.JobInfo(job))
+}
+
+func (c *SchedulerController) delayed(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/_sys/job/delayed"), "/")
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		jobs, err := c.scheduler.DelayedJobs(ctx, 1000)
+		if err != nil {
+			errors.Internal("failed to list delayed jobs, error=" + err.Error())
+		}
+		writeJSON(w, jobs)
+	case r.Method == http.MethodDelete && id != "":
+		slog.WarnContext(ctx, fmt.Sprintf("[MANUAL_OPERATION] cancel delayed job, id=%s", id))
+		actionlog.Context(&ctx, "manual_operation", true)
+		canceled, err := c.scheduler.CancelDelayed(ctx, id)
+		if err != nil {
+			errors.Internal("failed to cancel delayed job, error=" + err.Error())
+		}
+		if !canceled {
+			errors.NotFound("delayed job not found, id=" + id)
+		}
+		w.WriteHeader(200)
+		w.Write([]byte("delayed job canceled, id=" + id))
+	case r.Method == http.MethodPut && id != "":
+		runAt, err := time.Parse(time.RFC3339, r.URL.Query().Get("run_at"))
+		if err != nil {
+			errors.BadRequest("run_at must be RFC3339 time, e.g. 2024-01-02T15:04:05+08:00", "INVALID_RUN_AT")
+		}
+		slog.WarnContext(ctx, fmt.Sprintf("[MANUAL_OPERATION] reschedule delayed job, id=%s, runAt=%s", id, runAt))
+		actionlog.Context(&ctx, "manual_operation", true)
+		rescheduled, err := c.scheduler.RescheduleDelayed(ctx, id, runAt)
+		if err != nil {
+			errors.Internal("failed to reschedule delayed job, error=" + err.Error())
+		}
+		if !rescheduled {
+			errors.NotFound("delayed job not found, id=" + id)
+		}
+		w.WriteHeader(200)
+		w.Write([]byte("delayed job rescheduled, id=" + id + ", runAt=" + runAt.Format(time.RFC3339)))
+	default:
+		errors.NotFound("not found")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, result any) {
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Set("Pragma", "no-cache")
+	w.Header().Set("Expires", "0")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(json.Stringify(result))
 }
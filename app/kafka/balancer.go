@@ -0,0 +1,18 @@
+package kafka
+
+import (
+	"github.com/odycenter/std-library/internal/kafkahash"
+	"github.com/segmentio/kafka-go"
+)
+
+// keyHashBalancer partitions by kafkahash like kafka.KeyHashBalancer of kafka package, so a key goes to same partition with both producers
+type keyHashBalancer struct {
+	rr kafka.RoundRobin
+}
+
+func (b *keyHashBalancer) Balance(msg kafka.Message, partitions ...int) int {
+	if len(msg.Key) == 0 {
+		return b.rr.Balance(msg, partitions...)
+	}
+	return kafkahash.Partition(msg.Key, partitions)
+}
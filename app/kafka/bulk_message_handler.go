@@ -0,0 +1,105 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	actionlog "github.com/odycenter/std-library/app/log"
+	"github.com/odycenter/std-library/app/log/consts/logKey"
+	"github.com/segmentio/kafka-go"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// BulkMessageHandler handles messages in batch, the batch is committed once after Handle returns
+type BulkMessageHandler interface {
+	Handle(ctx context.Context, messages []Message)
+}
+
+type Message struct {
+	Key       string
+	Data      []byte
+	Partition int
+	Offset    int64
+	Time      time.Time
+}
+
+// HandleBulk processes messages under one action log, with partition/offset ranges in context
+func HandleBulk(clientId, groupId string, records []kafka.Message, process func(ctx context.Context, messages []Message)) (id string) {
+	id, _ = handleBulk(clientId, groupId, records, process)
+	return
+}
+
+func handleBulk(clientId, groupId string, records []kafka.Message, process func(ctx context.Context, messages []Message)) (id string, failure *HandleError) {
+	ctx := context.Background()
+	topic := records[0].Topic
+	actionLog := actionlog.Begin("topic:"+topic, "bulk-message-handler")
+	id = actionLog.Id
+	if groupId != "" {
+		actionLog.PutContext("kafka_group_id", groupId)
+	}
+	if clientId != "" {
+		actionLog.PutContext("kafka_client_id", clientId)
+	}
+	actionLog.PutContext("topic", topic)
+	actionLog.PutContext("message_count", len(records))
+	actionLog.PutContext("topic_offsets", offsetRanges(records)...)
+
+	messages := make([]Message, len(records))
+	refIds := make(map[string]bool)
+	for i, record := range records {
+		messages[i] = Message{Key: string(record.Key), Data: record.Value, Partition: record.Partition, Offset: record.Offset, Time: record.Time}
+		if refId := header(record, logKey.RefId, ""); refId != "" && !refIds[refId] {
+			refIds[refId] = true
+			actionLog.PutContext(logKey.RefId, refId)
+		}
+	}
+	statMap := make(map[string]float64)
+	contextMap := make(map[string][]any)
+	ctx = context.WithValue(ctx, logKey.Stat, statMap)
+	ctx = context.WithValue(ctx, logKey.Context, contextMap)
+	ctx = context.WithValue(ctx, logKey.Id, id)
+	ctx = context.WithValue(ctx, logKey.Action, actionLog.Action)
+	slog.DebugContext(ctx, fmt.Sprintf("[message] topic: %v, messages: %d", topic, len(records)))
+
+	CheckConsumerDelay(ctx, records[0], actionLog)
+
+	defer func() {
+		if err := recover(); err != nil {
+			failure = handleError(err)
+			actionLog.AddStat(statMap)
+
+			actionlog.HandleRecover(err, actionLog, contextMap)
+		}
+	}()
+
+	process(ctx, messages)
+	actionLog.AddContext(contextMap)
+	actionLog.AddStat(statMap)
+	actionlog.End(actionLog, "ok")
+
+	return
+}
+
+// offsetRanges returns "partition:first-last" sorted by partition
+func offsetRanges(records []kafka.Message) []any {
+	type offsetRange struct{ first, last int64 }
+	ranges := make(map[int]*offsetRange)
+	var partitions []int
+	for _, record := range records {
+		r, ok := ranges[record.Partition]
+		if !ok {
+			ranges[record.Partition] = &offsetRange{first: record.Offset, last: record.Offset}
+			partitions = append(partitions, record.Partition)
+			continue
+		}
+		r.first = min(r.first, record.Offset)
+		r.last = max(r.last, record.Offset)
+	}
+	sort.Ints(partitions)
+	result := make([]any, len(partitions))
+	for i, partition := range partitions {
+		result[i] = fmt.Sprintf("%d:%d-%d", partition, ranges[partition].first, ranges[partition].last)
+	}
+	return result
+}
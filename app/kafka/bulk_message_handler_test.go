@@ -0,0 +1,49 @@
+package kafka
+
+import (
+	"context"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+type bulkHandlerFunc func(ctx context.Context, messages []Message)
+
+func (f bulkHandlerFunc) Handle(ctx context.Context, messages []Message) {
+	f(ctx, messages)
+}
+
+func TestBulkHandle(t *testing.T) {
+	broker := newMemoryBroker()
+	for i := 0; i < 5; i++ {
+		_ = broker.WriteMessages(context.Background(), kafka.Message{Topic: "orders", Key: []byte("key"), Value: []byte("value")})
+	}
+
+	var mu sync.Mutex
+	var batches [][]Message
+	listener := &MessageListener{BulkHandler: bulkHandlerFunc(func(ctx context.Context, messages []Message) {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, messages)
+	})}
+	listener.SetPoolSize(1)
+	listener.Initialize(&SubscribeOption{Topic: "orders", GroupId: "group", MaxBatchSize: 3, MaxBatchWait: 50 * time.Millisecond})
+	listener.newReader = broker.reader
+	listener.Start(context.Background())
+	defer listener.cancel()
+
+	assert.Eventually(t, func() bool { return broker.committedOffset("orders") == 5 }, 5*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, batches, 2)
+	assert.Len(t, batches[0], 3)
+	assert.Len(t, batches[1], 2)
+	assert.Equal(t, int64(3), batches[1][0].Offset)
+}
+
+func TestOffsetRanges(t *testing.T) {
+	records := []kafka.Message{{Partition: 1, Offset: 10}, {Partition: 0, Offset: 5}, {Partition: 1, Offset: 12}, {Partition: 1, Offset: 11}}
+	assert.Equal(t, []any{"0:5-5", "1:10-12"}, offsetRanges(records))
+}
@@ -6,3 +6,16 @@ const (
 	FirstOffset = kafka.FirstOffset
 	LastOffset  = kafka.LastOffset
 )
+
+// headers of message forwarded to retry or dead letter topic
+const (
+	HeaderOriginalTopic     = "original_topic"
+	HeaderOriginalPartition = "original_partition"
+	HeaderOriginalOffset    = "original_offset"
+	HeaderRetryAttempt      = "retry_attempt"
+	HeaderRetryAt           = "retry_at" // unix millis, message is not handled before
+	HeaderErrorCode         = "error_code"
+	HeaderErrorMessage      = "error_message"
+	HeaderActionLogId       = "action_log_id"
+	HeaderMessageId         = "message_id" // set by Publisher, used by Dedup
+)
@@ -0,0 +1,138 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	actionlog "github.com/odycenter/std-library/app/log"
+	"github.com/odycenter/std-library/app/redis"
+	"github.com/segmentio/kafka-go"
+	"log"
+	"log/slog"
+	"time"
+)
+
+const defaultDedupClaimTimeout = time.Minute
+
+// DedupStore claims ids of messages before handling, RedisDedupStore and DBDedupStore are provided
+type DedupStore interface {
+	// Claim marks id as processing until timeout atomically, returns false if id is processed or being processed
+	Claim(ctx context.Context, id string, timeout time.Duration) (bool, error)
+	// Complete marks id as processed until ttl
+	Complete(ctx context.Context, id string, ttl time.Duration) error
+	// Release removes processing mark, so message can be handled again
+	Release(ctx context.Context, id string) error
+}
+
+// DedupPolicy skips message already processed or being processed by the group, id is claimed before handling and marked as processed after handled,
+// claim is released if handler failed, so failed or interrupted message can be handled again,
+// claim expires after ClaimTimeout, e.g. instance crashed during handling, it should be longer than handling time
+type DedupPolicy struct {
+	Store        DedupStore
+	TTL          time.Duration
+	ClaimTimeout time.Duration                        // default is 1 minute
+	Id           func(key string, data []byte) string // default is message_id header
+}
+
+// Dedup skips duplicate messages by message_id header, which is set by Publisher, or by id func
+func Dedup(store DedupStore, ttl time.Duration, id ...func(key string, data []byte) string) Option {
+	return func(opt *SubscribeOption) {
+		opt.Dedup = &DedupPolicy{Store: store, TTL: ttl}
+		if len(id) > 0 {
+			opt.Dedup.Id = id[0]
+		}
+	}
+}
+
+func (p *DedupPolicy) messageId(record kafka.Message) string {
+	if p.Id != nil {
+		return p.Id(string(record.Key), record.Value)
+	}
+	return header(record, HeaderMessageId, "")
+}
+
+// process wraps handler, store failure does not block message, it's handled as without dedup
+func (p *DedupPolicy) process(groupId, topic string, record kafka.Message, handle func(ctx context.Context, key string, data []byte)) func(ctx context.Context, key string, data []byte) {
+	messageId := p.messageId(record)
+	if messageId == "" {
+		return handle
+	}
+	return func(ctx context.Context, key string, data []byte) {
+		actionlog.Context(&ctx, "message_id", messageId)
+		id := groupId + ":" + topic + ":" + messageId
+		claimed, err := p.Store.Claim(ctx, id, p.claimTimeout())
+		if err != nil {
+			slog.WarnContext(ctx, fmt.Sprintf("[message-listener] failed to claim message, handle without dedup, id: %s, error: %v", id, err))
+			handle(ctx, key, data)
+			return
+		}
+		if !claimed {
+			actionlog.Context(&ctx, "duplicate", true)
+			slog.WarnContext(ctx, fmt.Sprintf("[message-listener] skip duplicate message, groupId: %s, topic: %s, messageId: %s", groupId, topic, messageId))
+			return
+		}
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := p.Store.Release(context.WithoutCancel(ctx), id); err != nil {
+				slog.WarnContext(ctx, fmt.Sprintf("[message-listener] failed to release claimed message, id: %s, error: %v", id, err))
+			}
+		}()
+		handle(ctx, key, data)
+		completed = true
+		if err := p.Store.Complete(ctx, id, p.TTL); err != nil {
+			slog.WarnContext(ctx, fmt.Sprintf("[message-listener] failed to record processed message, id: %s, error: %v", id, err))
+		}
+	}
+}
+
+func (p *DedupPolicy) claimTimeout() time.Duration {
+	if p.ClaimTimeout > 0 {
+		return p.ClaimTimeout
+	}
+	return defaultDedupClaimTimeout
+}
+
+// dedupRedis is redis operations used by RedisDedupStore, client of RedisConfig implements it
+type dedupRedis interface {
+	redis.Redis
+	redis.ConditionalSetter
+	redis.Scripter
+}
+
+type RedisDedupStore struct {
+	redis dedupRedis
+}
+
+func NewRedisDedupStore(client redis.Redis) *RedisDedupStore {
+	dedup, ok := client.(dedupRedis)
+	if !ok {
+		log.Panicf("redis client does not support SetNX and Eval, type=%T", client)
+	}
+	return &RedisDedupStore{redis: dedup}
+}
+
+const (
+	dedupProcessing    = "processing"
+	dedupProcessed     = "processed"
+	releaseDedupScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`
+)
+
+func (s *RedisDedupStore) Claim(ctx context.Context, id string, timeout time.Duration) (bool, error) {
+	return s.redis.SetNX(ctx, "dedup:"+id, dedupProcessing, timeout)
+}
+
+func (s *RedisDedupStore) Complete(ctx context.Context, id string, ttl time.Duration) error {
+	_, err := s.redis.Set(ctx, "dedup:"+id, dedupProcessed, ttl)
+	return err
+}
+
+// Release deletes key only if it's still processing, processed mark is kept
+func (s *RedisDedupStore) Release(ctx context.Context, id string) error {
+	_, err := s.redis.Eval(ctx, releaseDedupScript, []string{"dedup:" + id}, dedupProcessing)
+	return err
+}
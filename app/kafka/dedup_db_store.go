@@ -0,0 +1,97 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/odycenter/std-library/dbase"
+	"strings"
+	"time"
+)
+
+// DedupDDL returns idempotent statements to create table for DBDedupStore, execute them one by one
+func DedupDDL(dialect dbase.Dialect, table string) []string {
+	if dialect == dbase.Postgres {
+		return []string{
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+    id VARCHAR(255) NOT NULL PRIMARY KEY,
+    processed SMALLINT NOT NULL DEFAULT 0,
+    expired_at BIGINT NOT NULL
+)`, table),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS ix_%[1]s_expired_at ON %[1]s (expired_at)`, table),
+		}
+	}
+	return []string{fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s (
+    id VARCHAR(255) NOT NULL PRIMARY KEY,
+    processed TINYINT NOT NULL DEFAULT 0,
+    expired_at BIGINT NOT NULL,
+    INDEX ix_%[1]s_expired_at (expired_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`, table)}
+}
+
+// DBDedupStore records ids in table of db registered by orm alias, expired rows are deleted by Purge,
+// which is scheduled hourly by KafkaConfig for subscriptions using the store
+type DBDedupStore struct {
+	alias string
+	table string
+}
+
+func NewDBDedupStore(alias, table string) *DBDedupStore {
+	return &DBDedupStore{alias: alias, table: table}
+}
+
+// Claim inserts processing row, or takes over expired row, e.g. claim of crashed instance or expired processed row not purged yet
+func (s *DBDedupStore) Claim(ctx context.Context, id string, timeout time.Duration) (bool, error) {
+	db := orm.NewOrmUsingDB(s.alias)
+	now := time.Now()
+	expiredAt := now.Add(timeout).UnixMilli()
+	_, err := db.Raw("INSERT INTO "+s.table+" (id, processed, expired_at) VALUES (?, 0, ?)", id, expiredAt).Exec()
+	if err == nil {
+		return true, nil
+	}
+	if !duplicate(err) {
+		return false, err
+	}
+	result, err := db.Raw("UPDATE "+s.table+" SET processed = 0, expired_at = ? WHERE id = ? AND expired_at <= ?", expiredAt, id, now.UnixMilli()).Exec()
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+func (s *DBDedupStore) Complete(ctx context.Context, id string, ttl time.Duration) error {
+	db := orm.NewOrmUsingDB(s.alias)
+	expiredAt := time.Now().Add(ttl).UnixMilli()
+	result, err := db.Raw("UPDATE "+s.table+" SET processed = 1, expired_at = ? WHERE id = ?", expiredAt, id).Exec()
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows > 0 {
+		return nil
+	}
+	_, err = db.Raw("INSERT INTO "+s.table+" (id, processed, expired_at) VALUES (?, 1, ?)", id, expiredAt).Exec()
+	if err != nil && duplicate(err) { // mysql reports 0 affected row for unchanged value
+		return nil
+	}
+	return err
+}
+
+func (s *DBDedupStore) Release(ctx context.Context, id string) error {
+	_, err := orm.NewOrmUsingDB(s.alias).Raw("DELETE FROM "+s.table+" WHERE id = ? AND processed = 0", id).Exec()
+	return err
+}
+
+// Purge deletes expired ids
+func (s *DBDedupStore) Purge(ctx context.Context) (int64, error) {
+	result, err := orm.NewOrmUsingDB(s.alias).Raw("DELETE FROM "+s.table+" WHERE expired_at <= ?", time.Now().UnixMilli()).Exec()
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func duplicate(err error) bool {
+	message := err.Error()
+	return strings.Contains(message, "Duplicate entry") || strings.Contains(message, "duplicate key")
+}
@@ -0,0 +1,132 @@
+package kafka
+
+import (
+	"context"
+	"github.com/odycenter/std-library/dbase"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memoryDedupStore keeps ttl of processed ids and claimed ids
+type memoryDedupStore struct {
+	mu       sync.Mutex
+	ids      map[string]time.Duration
+	claimed  map[string]bool
+	releases int
+}
+
+func newMemoryDedupStore() *memoryDedupStore {
+	return &memoryDedupStore{ids: make(map[string]time.Duration), claimed: make(map[string]bool)}
+}
+
+func (s *memoryDedupStore) Claim(_ context.Context, id string, _ time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.ids[id]; ok || s.claimed[id] {
+		return false, nil
+	}
+	s.claimed[id] = true
+	return true, nil
+}
+
+func (s *memoryDedupStore) Complete(_ context.Context, id string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.claimed, id)
+	s.ids[id] = ttl
+	return nil
+}
+
+func (s *memoryDedupStore) Release(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.claimed, id)
+	s.releases++
+	return nil
+}
+
+func TestDedup(t *testing.T) {
+	broker := newMemoryBroker()
+	for _, id := range []string{"m1", "m2", "m1", "", ""} {
+		message := kafka.Message{Topic: "orders", Value: []byte(id)}
+		if id != "" {
+			message.Headers = []kafka.Header{{Key: HeaderMessageId, Value: []byte(id)}}
+		}
+		_ = broker.WriteMessages(context.Background(), message)
+	}
+	store := newMemoryDedupStore()
+	opt := &SubscribeOption{Topic: "orders", GroupId: "group"}
+	Dedup(store, time.Hour)(opt)
+
+	var mu sync.Mutex
+	var handled []string
+	listener := startListener(broker, opt, func(ctx context.Context, key string, data []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, string(data))
+	})
+	defer listener.cancel()
+
+	assert.Eventually(t, func() bool { return broker.committedOffset("orders") == 5 }, 5*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"m1", "m2", "", ""}, handled, "message without id is always handled")
+	assert.Equal(t, map[string]time.Duration{"group:orders:m1": time.Hour, "group:orders:m2": time.Hour}, store.ids)
+}
+
+func TestDedupFailedMessageNotRecorded(t *testing.T) {
+	store := newMemoryDedupStore()
+	policy := &DedupPolicy{Store: store, TTL: time.Hour, Id: func(key string, data []byte) string { return key }}
+	process := policy.process("group", "orders", kafka.Message{Key: []byte("order-1")}, func(ctx context.Context, key string, data []byte) {
+		panic("failed")
+	})
+
+	assert.Panics(t, func() { process(context.Background(), "order-1", nil) })
+	assert.Empty(t, store.ids)
+	assert.Empty(t, store.claimed, "claim is released, so message can be handled again")
+	assert.Equal(t, 1, store.releases)
+}
+
+func TestDedupConcurrentDuplicate(t *testing.T) {
+	store := newMemoryDedupStore()
+	policy := &DedupPolicy{Store: store, TTL: time.Hour, Id: func(key string, data []byte) string { return key }}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var handled int32
+	handler := func(ctx context.Context, key string, data []byte) {
+		if atomic.AddInt32(&handled, 1) == 1 {
+			close(started)
+			<-release
+		}
+	}
+	record := kafka.Message{Key: []byte("order-1")}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		policy.process("group", "orders", record, handler)(context.Background(), "order-1", nil)
+	}()
+	<-started
+	policy.process("group", "orders", record, handler)(context.Background(), "order-1", nil)
+	close(release)
+	<-done
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&handled), "duplicate being processed is skipped")
+	assert.Equal(t, map[string]time.Duration{"group:orders:order-1": time.Hour}, store.ids)
+	assert.Empty(t, store.claimed)
+}
+
+func TestDedupDDL(t *testing.T) {
+	mysql := DedupDDL(dbase.MySQL, "dedup")
+	assert.Len(t, mysql, 1)
+	assert.Contains(t, mysql[0], "CREATE TABLE IF NOT EXISTS dedup")
+	assert.Contains(t, mysql[0], "INDEX ix_dedup_expired_at (expired_at)")
+
+	postgres := DedupDDL(dbase.Postgres, "dedup")
+	assert.Len(t, postgres, 2)
+	assert.Equal(t, "CREATE INDEX IF NOT EXISTS ix_dedup_expired_at ON dedup (expired_at)", postgres[1])
+}
@@ -0,0 +1,167 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"log/slog"
+	"time"
+)
+
+const pauseCheckInterval = 100 * time.Millisecond
+
+// Pause stops fetching messages, readers keep heartbeat so the consumer group is not rebalanced
+func (m *MessageListener) Pause() {
+	m.paused.Store(true)
+	slog.Warn(fmt.Sprintf("[message-listener] paused, groupId: %s, topic: %s", m.Opt.GroupId, m.topic))
+}
+
+func (m *MessageListener) Resume() {
+	m.paused.Store(false)
+	slog.Warn(fmt.Sprintf("[message-listener] resumed, groupId: %s, topic: %s", m.Opt.GroupId, m.topic))
+}
+
+func (m *MessageListener) Paused() bool {
+	return m.paused.Load()
+}
+
+func (m *MessageListener) Topic() string {
+	return m.topic
+}
+
+// ReaderStats returns stats of readers, counters are reset on each call
+func (m *MessageListener) ReaderStats() []kafka.ReaderStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var stats []kafka.ReaderStats
+	for _, reader := range m.reader {
+		if r, ok := reader.(interface{ Stats() kafka.ReaderStats }); ok {
+			stats = append(stats, r.Stats())
+		}
+	}
+	return stats
+}
+
+// ResetOffset commits group offsets of topic to FirstOffset or LastOffset, returns committed offset per partition
+func (m *MessageListener) ResetOffset(ctx context.Context, offset int64) (map[int]int64, error) {
+	if offset != FirstOffset && offset != LastOffset {
+		return nil, fmt.Errorf("offset must be FirstOffset or LastOffset, offset=%d", offset)
+	}
+	return m.resetOffset(ctx, offset)
+}
+
+// ResetOffsetByTime commits group offsets of topic to the first message whose timestamp is not before the time,
+// partitions without such message are reset to latest
+func (m *MessageListener) ResetOffsetByTime(ctx context.Context, at time.Time) (map[int]int64, error) {
+	return m.resetOffset(ctx, at.UnixMilli())
+}
+
+// resetOffset commits offsets with a temporary group member, which triggers rebalance twice (join and leave),
+// after that all members fetch from the committed offsets, and commits of in-flight messages are rejected due to generation changed,
+// it is recommended to pause listener on all instances before reset, to avoid messages being handled during rebalance
+func (m *MessageListener) resetOffset(ctx context.Context, timestamp int64) (map[int]int64, error) {
+	offsets, err := m.offsets(ctx, m.topic, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]kafka.Message, 0, len(offsets))
+	for partition, offset := range offsets {
+		messages = append(messages, kafka.Message{Topic: m.topic, Partition: partition, Offset: offset - 1}) // committed offset is message offset + 1
+	}
+
+	reader := m.newReader(ClientID(), m.topic, m.Opt)
+	defer reader.Close()
+	innerCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	err = reader.CommitMessages(innerCtx, messages...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit offsets, groupId: %s, topic: %s, error: %w", m.Opt.GroupId, m.topic, err)
+	}
+	slog.WarnContext(ctx, fmt.Sprintf("[message-listener] reset offsets, groupId: %s, topic: %s, offsets: %v", m.Opt.GroupId, m.topic, offsets))
+	return offsets, nil
+}
+
+// listOffsets returns offset of each partition by timestamp, which is FirstOffset, LastOffset or unix millis
+func listOffsets(ctx context.Context, opt *SubscribeOption, topic string, timestamp int64) (map[int]int64, error) {
+	client := &kafka.Client{Addr: kafka.TCP(opt.getBrokers()...), Timeout: 10 * time.Second, Transport: opt.Security.Transport()}
+	metadata, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, err
+	}
+	var partitions []int
+	for _, t := range metadata.Topics {
+		if t.Error != nil {
+			return nil, t.Error
+		}
+		for _, p := range t.Partitions {
+			partitions = append(partitions, p.ID)
+		}
+	}
+	if len(partitions) == 0 {
+		return nil, fmt.Errorf("topic not found, topic=%s", topic)
+	}
+
+	latest, err := partitionOffsets(ctx, client, topic, partitions, LastOffset)
+	if err != nil || timestamp == LastOffset {
+		return latest, err
+	}
+	offsets, err := partitionOffsets(ctx, client, topic, partitions, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	for partition, offset := range offsets {
+		if offset < 0 {
+			offsets[partition] = latest[partition]
+		}
+	}
+	return offsets, nil
+}
+
+func partitionOffsets(ctx context.Context, client *kafka.Client, topic string, partitions []int, timestamp int64) (map[int]int64, error) {
+	requests := make([]kafka.OffsetRequest, len(partitions))
+	for i, partition := range partitions {
+		requests[i] = kafka.OffsetRequest{Partition: partition, Timestamp: timestamp}
+	}
+	response, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: map[string][]kafka.OffsetRequest{topic: requests}})
+	if err != nil {
+		return nil, err
+	}
+	offsets := make(map[int]int64, len(partitions))
+	for _, p := range response.Topics[topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("failed to list offsets, topic: %s, partition: %d, error: %w", topic, p.Partition, p.Error)
+		}
+		switch timestamp {
+		case FirstOffset:
+			offsets[p.Partition] = p.FirstOffset
+		case LastOffset:
+			offsets[p.Partition] = p.LastOffset
+		default:
+			offsets[p.Partition] = -1 // no message after timestamp
+			for offset := range p.Offsets {
+				offsets[p.Partition] = offset
+			}
+		}
+	}
+	return offsets, nil
+}
+
+// committedOffsets returns committed offset of group per partition, -1 if group has not committed to partition
+func committedOffsets(ctx context.Context, opt *SubscribeOption, topic string, partitions []int) (map[int]int64, error) {
+	client := &kafka.Client{Addr: kafka.TCP(opt.getBrokers()...), Timeout: 10 * time.Second, Transport: opt.Security.Transport()}
+	response, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{GroupID: opt.GroupId, Topics: map[string][]int{topic: partitions}})
+	if err != nil {
+		return nil, err
+	}
+	if response.Error != nil {
+		return nil, response.Error
+	}
+	offsets := make(map[int]int64, len(partitions))
+	for _, p := range response.Topics[topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("failed to fetch committed offset, groupId: %s, topic: %s, partition: %d, error: %w", opt.GroupId, topic, p.Partition, p.Error)
+		}
+		offsets[p.Partition] = p.CommittedOffset
+	}
+	return offsets, nil
+}
@@ -0,0 +1,57 @@
+package kafka
+
+import (
+	"context"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPauseAndResume(t *testing.T) {
+	broker := newMemoryBroker()
+	_ = broker.WriteMessages(context.Background(), kafka.Message{Topic: "orders", Value: []byte("value")})
+	var handled int32
+	listener := &MessageListener{Handler: handlerFunc(func(ctx context.Context, key string, data []byte) {
+		atomic.AddInt32(&handled, 1)
+	})}
+	listener.SetPoolSize(1)
+	listener.Initialize(&SubscribeOption{Topic: "orders", GroupId: "group"})
+	listener.newReader = broker.reader
+	listener.Pause()
+	listener.Start(context.Background())
+	defer listener.cancel()
+
+	time.Sleep(3 * pauseCheckInterval)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&handled))
+
+	listener.Resume()
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&handled) == 1 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestResetOffset(t *testing.T) {
+	broker := newMemoryBroker()
+	listener := &MessageListener{}
+	listener.Initialize(&SubscribeOption{Topic: "orders", GroupId: "group"})
+	listener.newReader = broker.reader
+	var requested int64
+	listener.offsets = func(ctx context.Context, topic string, timestamp int64) (map[int]int64, error) {
+		requested = timestamp
+		return map[int]int64{0: 5}, nil
+	}
+
+	offsets, err := listener.ResetOffset(context.Background(), FirstOffset)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]int64{0: 5}, offsets)
+	assert.Equal(t, FirstOffset, requested)
+	assert.Equal(t, int64(5), broker.committedOffset("orders"))
+
+	at := time.Now()
+	_, err = listener.ResetOffsetByTime(context.Background(), at)
+	assert.NoError(t, err)
+	assert.Equal(t, at.UnixMilli(), requested)
+
+	_, err = listener.ResetOffset(context.Background(), 10)
+	assert.Error(t, err)
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	internal "github.com/odycenter/std-library/app/internal/module"
+	"github.com/odycenter/std-library/app/web/metric"
 	"github.com/segmentio/kafka-go"
 	"log/slog"
 	"sync"
@@ -12,13 +13,28 @@ import (
 	"time"
 )
 
+// MessageReader fetches and commits messages of consumer group, *kafka.Reader implements it
+type MessageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
 type MessageListener struct {
 	topic        string
 	Opt          *SubscribeOption
 	Handler      MessageHandler
+	BulkHandler  BulkMessageHandler // used instead of Handler if set
 	ctx          context.Context
 	cancel       context.CancelFunc
-	reader       []*kafka.Reader
+	reader       []MessageReader
+	writer       MessageWriter // to publish failed messages, only created if retry or dead letter is configured
+	newReader    func(clientId, topic string, opt *SubscribeOption) MessageReader
+	newWriter    func(opt *SubscribeOption) MessageWriter
+	offsets      func(ctx context.Context, topic string, timestamp int64) (map[int]int64, error)
+	committed    func(ctx context.Context, topic string, partitions []int) (map[int]int64, error)
+	lagInterval  time.Duration
+	paused       atomic.Bool
 	poolSize     int
 	runningTasks int32
 	mu           sync.Mutex
@@ -36,39 +52,108 @@ func (m *MessageListener) Initialize(opt *SubscribeOption) {
 	m.Opt = opt
 	m.topic = opt.Topic
 	m.ctx, m.cancel = context.WithCancel(context.Background())
+	m.newReader = func(clientId, topic string, opt *SubscribeOption) MessageReader {
+		return topicReader(clientId, topic, opt)
+	}
+	m.newWriter = forwardWriter
+	m.offsets = func(ctx context.Context, topic string, timestamp int64) (map[int]int64, error) {
+		return listOffsets(ctx, m.Opt, topic, timestamp)
+	}
+	m.committed = func(ctx context.Context, topic string, partitions []int) (map[int]int64, error) {
+		return committedOffsets(ctx, m.Opt, topic, partitions)
+	}
+	m.lagInterval = lagPollInterval
 }
 
 func (m *MessageListener) Start(ctx context.Context) {
+	if m.Opt.forwardEnabled() {
+		m.writer = m.newWriter(m.Opt)
+	}
 	for i := 0; i < m.poolSize; i++ {
 		clientId := ClientID()
 		slog.InfoContext(ctx, fmt.Sprintf("[message-listener] start message listener, groupId: %s, topic: %s, clientID: %s", m.Opt.GroupId, m.topic, clientId))
 		go m.Run(clientId)
 	}
+	// retry topics are consumed by one reader each, messages wait for backoff in order
+	for _, topic := range m.Opt.retryTopics() {
+		clientId := ClientID()
+		slog.InfoContext(ctx, fmt.Sprintf("[message-listener] start retry message listener, groupId: %s, topic: %s, clientID: %s", m.Opt.GroupId, topic, clientId))
+		go m.consume(clientId, topic)
+	}
+	go m.pollLag()
 }
 
 func (m *MessageListener) Run(clientId string) {
-	reader := Reader(clientId, m.Opt)
+	m.consume(clientId, m.topic)
+}
+
+func (m *MessageListener) consume(clientId, topic string) {
+	reader := m.newReader(clientId, topic, m.Opt)
 	m.mu.Lock()
 	m.reader = append(m.reader, reader)
 	m.mu.Unlock()
+	var dispatcher *orderedDispatcher
+	if m.Opt.Workers > 0 && m.BulkHandler == nil && topic == m.topic { // retry topics are handled sequentially
+		dispatcher = newOrderedDispatcher(m, clientId, reader, m.Opt.Workers)
+		defer dispatcher.close()
+	}
 	for {
 		select {
 		case <-m.ctx.Done():
-			slog.Warn(fmt.Sprintf("[message-listener] ctx.Done by caller. groupId: %s, topic: %s, clientID: %s", m.Opt.GroupId, m.topic, clientId))
+			slog.Warn(fmt.Sprintf("[message-listener] ctx.Done by caller. groupId: %s, topic: %s, clientID: %s", m.Opt.GroupId, topic, clientId))
 			return
 		default:
 			if internal.IsShutdown() {
-				slog.Info(fmt.Sprintf("[message-listener] reject kafka handle process due to server is shutting down!! GroupId: %s, topic: %s, clientId: %s", m.Opt.GroupId, m.topic, clientId))
+				slog.Info(fmt.Sprintf("[message-listener] reject kafka handle process due to server is shutting down!! GroupId: %s, topic: %s, clientId: %s", m.Opt.GroupId, topic, clientId))
 				return
 			}
+			if m.Paused() {
+				time.Sleep(pauseCheckInterval)
+				continue
+			}
+			if dispatcher != nil {
+				m.fetchAndDispatch(clientId, reader, dispatcher)
+				continue
+			}
 			m.run(clientId, reader)
 		}
 	}
 }
 
-func (m *MessageListener) run(clientId string, reader *kafka.Reader) {
+func (m *MessageListener) taskStarted() {
 	atomic.AddInt32(&m.runningTasks, 1)
-	defer atomic.AddInt32(&m.runningTasks, -1)
+	metric.KafkaListenerRunningTasks.WithLabelValues(m.topic, m.Opt.GroupId).Inc()
+}
+
+func (m *MessageListener) taskCompleted() {
+	atomic.AddInt32(&m.runningTasks, -1)
+	metric.KafkaListenerRunningTasks.WithLabelValues(m.topic, m.Opt.GroupId).Dec()
+}
+
+// fetchAndDispatch hands message to worker by key, running tasks are counted per dispatched message until handled
+func (m *MessageListener) fetchAndDispatch(clientId string, reader MessageReader, dispatcher *orderedDispatcher) {
+	innerCtx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	msg, err := reader.FetchMessage(innerCtx)
+	if err != nil {
+		if !errors.Is(err, context.DeadlineExceeded) {
+			slog.Error(fmt.Sprintf("[message-listener] FetchMessage fail, groupId: %s, topic: %s, clientId: %s, error: %v", m.Opt.GroupId, m.topic, clientId, err))
+		}
+		return
+	}
+	recordFetched(m.Opt.GroupId, msg)
+	dispatcher.dispatch(msg)
+}
+
+func (m *MessageListener) run(clientId string, reader MessageReader) {
+	m.taskStarted()
+	defer m.taskCompleted()
+
+	if m.BulkHandler != nil {
+		m.runBulk(clientId, reader)
+		return
+	}
 
 	innerCtx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
@@ -82,11 +167,81 @@ func (m *MessageListener) run(clientId string, reader *kafka.Reader) {
 		return
 	}
 
-	id := Handle(clientId, m.Opt.GroupId, msg, m.Handler.Handle)
+	recordFetched(m.Opt.GroupId, msg)
+	if !m.awaitRetry(msg) {
+		return // not committed, message will be redelivered
+	}
+	start := time.Now()
+	id, failure := handle(clientId, m.Opt.GroupId, msg, m.process(msg))
+	recordHandled(msg.Topic, m.Opt.GroupId, start, 1, failure)
+	if failure != nil && m.writer != nil && !m.forward(msg, id, failure) {
+		return
+	}
 	err = reader.CommitMessages(context.Background(), msg)
 	if err != nil {
 		slog.Error(fmt.Sprintf("[message-listener] CommitMessages fail, groupId: %s, topic: %s, clientId: %s, id: %v, error: %v", m.Opt.GroupId, m.topic, clientId, id, err))
+		return
+	}
+	recordCommitted(msg.Topic, m.Opt.GroupId, 1)
+}
+
+func (m *MessageListener) process(msg kafka.Message) func(ctx context.Context, key string, data []byte) {
+	if m.Opt.Dedup == nil {
+		return m.Handler.Handle
 	}
+	return m.Opt.Dedup.process(m.Opt.GroupId, m.topic, msg, m.Handler.Handle)
+}
+
+// runBulk fetches up to MaxBatchSize messages or until MaxBatchWait elapsed, and commits once after batch handled
+func (m *MessageListener) runBulk(clientId string, reader MessageReader) {
+	var messages []kafka.Message
+	deadline := time.Now().Add(m.Opt.MaxBatchWait)
+	for len(messages) < m.Opt.MaxBatchSize {
+		timeout := time.Until(deadline)
+		if len(messages) == 0 {
+			timeout = time.Second * 5 // same as single message fetch, batch wait starts from first message
+		}
+		if timeout <= 0 {
+			break
+		}
+		innerCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		msg, err := reader.FetchMessage(innerCtx)
+		cancel()
+		if err != nil {
+			if !errors.Is(err, context.DeadlineExceeded) {
+				slog.Error(fmt.Sprintf("[message-listener] FetchMessage fail, groupId: %s, topic: %s, clientId: %s, error: %v", m.Opt.GroupId, m.topic, clientId, err))
+			}
+			break
+		}
+		recordFetched(m.Opt.GroupId, msg)
+		if !m.awaitRetry(msg) {
+			return
+		}
+		if len(messages) == 0 {
+			deadline = time.Now().Add(m.Opt.MaxBatchWait)
+		}
+		messages = append(messages, msg)
+	}
+	if len(messages) == 0 {
+		return
+	}
+
+	start := time.Now()
+	id, failure := handleBulk(clientId, m.Opt.GroupId, messages, m.BulkHandler.Handle)
+	recordHandled(messages[0].Topic, m.Opt.GroupId, start, len(messages), failure)
+	if failure != nil && m.writer != nil {
+		for _, msg := range messages {
+			if !m.forward(msg, id, failure) {
+				return
+			}
+		}
+	}
+	err := reader.CommitMessages(context.Background(), messages...)
+	if err != nil {
+		slog.Error(fmt.Sprintf("[message-listener] CommitMessages fail, groupId: %s, topic: %s, clientId: %s, id: %v, error: %v", m.Opt.GroupId, m.topic, clientId, id, err))
+		return
+	}
+	recordCommitted(messages[0].Topic, m.Opt.GroupId, len(messages))
 }
 
 func (m *MessageListener) RunningTasks() int {
@@ -107,14 +262,24 @@ func (m *MessageListener) AwaitTermination(ctx context.Context, timeoutInMs int6
 			for _, reader := range m.reader {
 				go reader.Close()
 			}
+			m.closeWriter()
 			return
 		default:
 			if m.RunningTasks() == 0 {
 				slog.InfoContext(innerCtx, fmt.Sprintf("all message handler have completed, groupId: %s, topic: %s", m.Opt.GroupId, m.topic))
+				m.closeWriter()
 				return
 			}
 			time.Sleep(100 * time.Millisecond)
 		}
 	}
+}
 
+func (m *MessageListener) closeWriter() {
+	if m.writer == nil {
+		return
+	}
+	if err := m.writer.Close(); err != nil {
+		slog.Error(fmt.Sprintf("[message-listener] failed to close writer, groupId: %s, topic: %s, error: %v", m.Opt.GroupId, m.topic, err))
+	}
 }
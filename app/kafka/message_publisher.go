@@ -0,0 +1,97 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	app "github.com/odycenter/std-library/app/conf"
+	actionlog "github.com/odycenter/std-library/app/log"
+	"github.com/odycenter/std-library/app/log/consts/logKey"
+	"github.com/odycenter/std-library/app/log/util"
+	"github.com/segmentio/kafka-go"
+	"log/slog"
+	"time"
+)
+
+// Producer is shared by all publishers of kafka config, and closed at shutdown after all tasks completed
+type Producer struct {
+	writer MessageWriter
+}
+
+// NewProducer creates producer, security is optional
+func NewProducer(brokers []string, security *Security) *Producer {
+	return &Producer{writer: &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Balancer:               &keyHashBalancer{},
+		RequiredAcks:           kafka.RequireOne,
+		BatchTimeout:           10 * time.Millisecond, // publish is sync, not to wait for batch to fill up
+		AllowAutoTopicCreation: true,
+		Transport:              security.Transport(),
+	}}
+}
+
+func (p *Producer) Publisher(topic string) *Publisher {
+	return &Publisher{topic: topic, producer: p}
+}
+
+// PublishWithRefId publishes message with given ref_id, e.g. relay message saved by other action
+func (p *Producer) PublishWithRefId(ctx context.Context, topic, refId, key string, data []byte) error {
+	return p.Publisher(topic).publish(ctx, refId, key, data)
+}
+
+// Close flushes pending messages and closes connections
+func (p *Producer) Close(ctx context.Context) {
+	slog.InfoContext(ctx, "close kafka producer")
+	err := p.writer.Close()
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("failed to close kafka producer, error: %v", err))
+	}
+}
+
+// Publisher publishes message to topic, id of current action log is propagated as ref_id header,
+// so the consumer action log links back to the producing request
+type Publisher struct {
+	topic    string
+	producer *Producer
+}
+
+func (p *Publisher) Publish(ctx context.Context, key string, data []byte) error {
+	id := actionlog.GetId(&ctx)
+	if id == "" { // not within action, e.g. background task, create action log to trace the message
+		actionLog := actionlog.Begin("topic:"+p.topic, "message-publisher")
+		actionLog.PutContext("topic", p.topic)
+		actionLog.RequestBody = string(data)
+		id = actionLog.Id
+		err := p.publish(ctx, id, key, data)
+		if err != nil {
+			actionlog.HandleRecover(err, actionLog, nil)
+			return err
+		}
+		actionlog.End(actionLog, "ok")
+		return nil
+	}
+
+	start := time.Now()
+	err := p.publish(ctx, id, key, data)
+	actionlog.Stat(&ctx, "kafka_publish_count", actionlog.GetStat(&ctx, "kafka_publish_count")+1)
+	actionlog.Stat(&ctx, "kafka_publish_elapsed", actionlog.GetStat(&ctx, "kafka_publish_elapsed")+float64(time.Since(start).Nanoseconds()))
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("failed to publish message, topic: %s, key: %s, error: %v", p.topic, key, err))
+	}
+	return err
+}
+
+func (p *Publisher) publish(ctx context.Context, refId, key string, data []byte) error {
+	message := kafka.Message{Topic: p.topic, Value: data}
+	if key != "" {
+		message.Key = []byte(key)
+	}
+	message.Headers = append(message.Headers,
+		kafka.Header{Key: logKey.RefId, Value: []byte(refId)},
+		kafka.Header{Key: HeaderMessageId, Value: []byte(util.GetIDGenerator().Next(time.Now()))},
+		kafka.Header{Key: logKey.ClientHostname, Value: []byte(app.LocalHostName())})
+	if app.Name != "" {
+		message.Headers = append(message.Headers, kafka.Header{Key: logKey.Client, Value: []byte(app.Name)})
+	}
+	slog.DebugContext(ctx, fmt.Sprintf("[publish] topic: %s, key: %s, message: %s", p.topic, key, data))
+	return p.producer.writer.WriteMessages(ctx, message)
+}
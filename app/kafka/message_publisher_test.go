@@ -0,0 +1,35 @@
+package kafka
+
+import (
+	"context"
+	"github.com/odycenter/std-library/app/log/consts/logKey"
+	"github.com/odycenter/std-library/internal/kafkahash"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPublisherPublish(t *testing.T) {
+	broker := newMemoryBroker()
+	publisher := (&Producer{writer: broker}).Publisher("orders")
+
+	ctx := context.WithValue(context.Background(), logKey.Id, "action-id")
+	ctx = context.WithValue(ctx, logKey.Stat, make(map[string]float64))
+	assert.NoError(t, publisher.Publish(ctx, "key", []byte("value")))
+	assert.NoError(t, publisher.Publish(context.Background(), "", []byte("value")))
+
+	messages := broker.messages("orders")
+	assert.Len(t, messages, 2)
+	assert.Equal(t, []byte("key"), messages[0].Key)
+	assert.Equal(t, "action-id", header(messages[0], logKey.RefId, ""))
+	assert.Equal(t, float64(1), ctx.Value(logKey.Stat).(map[string]float64)["kafka_publish_count"])
+	assert.Nil(t, messages[1].Key)
+	assert.NotEmpty(t, header(messages[1], logKey.RefId, ""), "publisher action log id must be propagated if not within action")
+}
+
+func TestKeyHashBalancer(t *testing.T) {
+	balancer := &keyHashBalancer{}
+	partition := balancer.Balance(kafka.Message{Key: []byte("key")}, 0, 1, 2)
+	assert.Equal(t, partition, balancer.Balance(kafka.Message{Key: []byte("key")}, 0, 1, 2))
+	assert.Equal(t, int(kafkahash.Hash([]byte("key"))%3), partition)
+}
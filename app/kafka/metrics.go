@@ -0,0 +1,74 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	internal "github.com/odycenter/std-library/app/internal/module"
+	"github.com/odycenter/std-library/app/web/metric"
+	"github.com/segmentio/kafka-go"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+const lagPollInterval = 15 * time.Second
+
+func recordFetched(groupId string, msg kafka.Message) {
+	metric.KafkaMessagesConsumed.WithLabelValues(msg.Topic, groupId).Inc()
+}
+
+func recordHandled(topic, groupId string, start time.Time, count int, failure *HandleError) {
+	metric.KafkaHandlerDuration.WithLabelValues(topic, groupId).Observe(time.Since(start).Seconds())
+	if failure != nil {
+		metric.KafkaMessagesFailed.WithLabelValues(topic, groupId, failure.ErrorCode).Add(float64(count))
+	}
+}
+
+func recordCommitted(topic, groupId string, count int) {
+	metric.KafkaMessagesCommitted.WithLabelValues(topic, groupId).Add(float64(count))
+}
+
+// pollLag updates lag from committed offsets of group, rather than on fetch, so lag keeps growing while listener is stuck or paused
+func (m *MessageListener) pollLag() {
+	ticker := time.NewTicker(m.lagInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			if internal.IsShutdown() {
+				return
+			}
+			for _, topic := range append([]string{m.topic}, m.Opt.retryTopics()...) {
+				m.recordLag(topic)
+			}
+		}
+	}
+}
+
+func (m *MessageListener) recordLag(topic string) {
+	ctx, cancel := context.WithTimeout(m.ctx, m.lagInterval)
+	defer cancel()
+	latest, err := m.offsets(ctx, topic, LastOffset)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("[message-listener] failed to list offsets, groupId: %s, topic: %s, error: %v", m.Opt.GroupId, topic, err))
+		return
+	}
+	partitions := make([]int, 0, len(latest))
+	for partition := range latest {
+		partitions = append(partitions, partition)
+	}
+	committed, err := m.committed(ctx, topic, partitions)
+	if err != nil {
+		slog.Warn(fmt.Sprintf("[message-listener] failed to fetch committed offsets, groupId: %s, topic: %s, error: %v", m.Opt.GroupId, topic, err))
+		return
+	}
+	for partition, offset := range latest {
+		committedOffset, ok := committed[partition]
+		if !ok || committedOffset < 0 { // group has not committed yet
+			continue
+		}
+		metric.KafkaConsumerLag.WithLabelValues(topic, m.Opt.GroupId, strconv.Itoa(partition)).Set(float64(max(offset-committedOffset, 0)))
+	}
+}
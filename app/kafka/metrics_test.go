@@ -0,0 +1,56 @@
+package kafka
+
+import (
+	"context"
+	"github.com/odycenter/std-library/app/web/errors"
+	"github.com/odycenter/std-library/app/web/metric"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestListenerMetrics(t *testing.T) {
+	broker := newMemoryBroker()
+	for _, value := range []string{"ok", "failed", "ok"} {
+		_ = broker.WriteMessages(context.Background(), kafka.Message{Topic: "metrics", Value: []byte(value)})
+	}
+
+	listener := startListener(broker, &SubscribeOption{Topic: "metrics", GroupId: "group"},
+		func(ctx context.Context, key string, data []byte) {
+			if string(data) == "failed" {
+				errors.Internal("failed", "METRIC_FAILED")
+			}
+		})
+	defer listener.cancel()
+
+	assert.Eventually(t, func() bool { return broker.committedOffset("metrics") == 3 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, float64(3), testutil.ToFloat64(metric.KafkaMessagesConsumed.WithLabelValues("metrics", "group")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metric.KafkaMessagesFailed.WithLabelValues("metrics", "group", "METRIC_FAILED")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metric.KafkaConsumerLag.WithLabelValues("metrics", "group", "0")))
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(metric.KafkaMessagesCommitted.WithLabelValues("metrics", "group")) == 3
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestConsumerLagWithoutConsuming(t *testing.T) {
+	broker := newMemoryBroker()
+	listener := &MessageListener{Handler: handlerFunc(func(ctx context.Context, key string, data []byte) {})}
+	listener.SetPoolSize(1)
+	listener.Initialize(&SubscribeOption{Topic: "lag", GroupId: "group"})
+	listener.newReader = broker.reader
+	listener.offsets = broker.offsets
+	listener.committed = broker.committedOffsets
+	listener.lagInterval = 10 * time.Millisecond
+	listener.Pause()
+	listener.Start(context.Background())
+	defer listener.cancel()
+
+	lag := metric.KafkaConsumerLag.WithLabelValues("lag", "group", "0")
+	_ = broker.WriteMessages(context.Background(), kafka.Message{Topic: "lag", Value: []byte("1")}, kafka.Message{Topic: "lag", Value: []byte("2")})
+	assert.Eventually(t, func() bool { return testutil.ToFloat64(lag) == 2 }, time.Second, 10*time.Millisecond)
+	_ = broker.WriteMessages(context.Background(), kafka.Message{Topic: "lag", Value: []byte("3")})
+	assert.Eventually(t, func() bool { return testutil.ToFloat64(lag) == 3 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(0), broker.committedOffset("lag"))
+}
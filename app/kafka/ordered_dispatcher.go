@@ -0,0 +1,148 @@
+package kafka
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	internal "github.com/odycenter/std-library/app/internal/module"
+	"github.com/odycenter/std-library/internal/kafkahash"
+	"github.com/segmentio/kafka-go"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const workerQueueSize = 100
+
+// orderedDispatcher fans out messages of one reader to workers by key hash, messages with same key are handled in order,
+// offset of partition is committed only after all messages before it are handled
+type orderedDispatcher struct {
+	listener   *MessageListener
+	clientId   string
+	reader     MessageReader
+	workers    []chan *pendingMessage
+	partitions map[int]*partitionState
+	sequence   atomic.Uint32 // round-robin for messages without key
+	mu         sync.Mutex
+	commitMu   sync.Mutex
+	wg         sync.WaitGroup
+}
+
+// partitionState tracks one generation of partition, a new generation starts when fetched offset is rewound,
+// e.g. offset reset or partition reassigned and consumed from committed offset
+type partitionState struct {
+	pending   *list.List // pending messages in fetch order
+	fetched   int64      // last fetched message offset
+	committed int64      // last committed message offset, guarded by commitMu
+}
+
+type pendingMessage struct {
+	msg       kafka.Message
+	partition *partitionState
+	done      bool
+}
+
+func newOrderedDispatcher(listener *MessageListener, clientId string, reader MessageReader, workers int) *orderedDispatcher {
+	d := &orderedDispatcher{
+		listener:   listener,
+		clientId:   clientId,
+		reader:     reader,
+		workers:    make([]chan *pendingMessage, workers),
+		partitions: make(map[int]*partitionState),
+	}
+	for i := range d.workers {
+		d.workers[i] = make(chan *pendingMessage, workerQueueSize)
+		d.wg.Add(1)
+		go d.work(d.workers[i])
+	}
+	return d
+}
+
+// worker uses same key hashing as producer, so a key is always handled by same worker
+func (d *orderedDispatcher) worker(key []byte) int {
+	if len(key) == 0 {
+		return int(d.sequence.Add(1) % uint32(len(d.workers)))
+	}
+	return int(kafkahash.Hash(key) % uint32(len(d.workers)))
+}
+
+func (d *orderedDispatcher) dispatch(msg kafka.Message) {
+	pending := d.track(msg)
+	d.listener.taskStarted()
+	d.workers[d.worker(msg.Key)] <- pending
+}
+
+func (d *orderedDispatcher) track(msg kafka.Message) *pendingMessage {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	state, ok := d.partitions[msg.Partition]
+	if !ok || msg.Offset <= state.fetched {
+		state = &partitionState{pending: list.New(), committed: -1}
+		d.partitions[msg.Partition] = state
+	}
+	state.fetched = msg.Offset
+	pending := &pendingMessage{msg: msg, partition: state}
+	state.pending.PushBack(pending)
+	return pending
+}
+
+func (d *orderedDispatcher) work(queue chan *pendingMessage) {
+	defer d.wg.Done()
+	m := d.listener
+	for pending := range queue {
+		msg := pending.msg
+		if internal.IsShutdown() {
+			m.taskCompleted() // not committed, message will be redelivered
+			continue
+		}
+		start := time.Now()
+		id, failure := handle(d.clientId, m.Opt.GroupId, msg, m.process(msg))
+		recordHandled(msg.Topic, m.Opt.GroupId, start, 1, failure)
+		if failure == nil || m.writer == nil || m.forward(msg, id, failure) {
+			d.complete(pending, id)
+		}
+		m.taskCompleted()
+	}
+}
+
+// complete marks message handled, and commits the highest offset which all messages before are handled,
+// messages of previous generation are not committed, as partition is rewound and they will be fetched again
+func (d *orderedDispatcher) complete(handled *pendingMessage, id string) {
+	d.mu.Lock()
+	handled.done = true
+	state := handled.partition
+	var commit *kafka.Message
+	count := 0
+	for e := state.pending.Front(); e != nil && e.Value.(*pendingMessage).done; e = state.pending.Front() {
+		commit = &e.Value.(*pendingMessage).msg
+		state.pending.Remove(e)
+		count++
+	}
+	current := d.partitions[handled.msg.Partition] == state
+	d.mu.Unlock()
+	if commit == nil || !current {
+		return
+	}
+
+	d.commitMu.Lock()
+	defer d.commitMu.Unlock()
+	if state.committed >= commit.Offset {
+		return
+	}
+	err := d.reader.CommitMessages(context.Background(), *commit)
+	if err != nil {
+		slog.Error(fmt.Sprintf("[message-listener] CommitMessages fail, groupId: %s, topic: %s, clientId: %s, id: %v, error: %v", d.listener.Opt.GroupId, commit.Topic, d.clientId, id, err))
+		return
+	}
+	state.committed = commit.Offset
+	recordCommitted(commit.Topic, d.listener.Opt.GroupId, count)
+}
+
+// close waits for queued messages to be handled or skipped
+func (d *orderedDispatcher) close() {
+	for _, worker := range d.workers {
+		close(worker)
+	}
+	d.wg.Wait()
+}
@@ -0,0 +1,80 @@
+package kafka
+
+import (
+	"context"
+	"github.com/odycenter/std-library/internal/kafkahash"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestDispatcher(broker *memoryBroker) *orderedDispatcher {
+	listener := &MessageListener{}
+	listener.Initialize(&SubscribeOption{Topic: "orders", GroupId: "group"})
+	return &orderedDispatcher{listener: listener, reader: broker.reader("", "orders", nil), partitions: make(map[int]*partitionState)}
+}
+
+func TestOrderedDispatcherCommit(t *testing.T) {
+	broker := newMemoryBroker()
+	d := newTestDispatcher(broker)
+	var pending []*pendingMessage
+	for offset := int64(0); offset < 3; offset++ {
+		pending = append(pending, d.track(kafka.Message{Topic: "orders", Offset: offset}))
+	}
+
+	d.complete(pending[1], "id")
+	assert.Equal(t, int64(0), broker.committedOffset("orders"), "offset 0 is not handled yet")
+	d.complete(pending[0], "id")
+	assert.Equal(t, int64(2), broker.committedOffset("orders"))
+	d.complete(pending[2], "id")
+	assert.Equal(t, int64(3), broker.committedOffset("orders"))
+}
+
+func TestOrderedDispatcherCommitAfterRewind(t *testing.T) {
+	broker := newMemoryBroker()
+	d := newTestDispatcher(broker)
+	d.complete(d.track(kafka.Message{Topic: "orders", Offset: 0}), "id")
+	d.complete(d.track(kafka.Message{Topic: "orders", Offset: 1}), "id")
+	inflight := d.track(kafka.Message{Topic: "orders", Offset: 2})
+	assert.Equal(t, int64(2), broker.committedOffset("orders"))
+
+	// offset is reset to 0, messages are fetched again
+	rewound := d.track(kafka.Message{Topic: "orders", Offset: 0})
+	d.complete(inflight, "id")
+	assert.Equal(t, int64(2), broker.committedOffset("orders"), "message of previous generation is not committed")
+	d.complete(rewound, "id")
+	assert.Equal(t, int64(1), broker.committedOffset("orders"), "committed after rewind")
+}
+
+func TestOrderedProcessing(t *testing.T) {
+	broker := newMemoryBroker()
+	for i, key := range []string{"a", "b", "a", "b", "a", "b"} {
+		_ = broker.WriteMessages(context.Background(), kafka.Message{Topic: "orders", Key: []byte(key), Value: []byte{byte('0' + i)}})
+	}
+
+	var mu sync.Mutex
+	handled := make(map[string]string)
+	var order []string
+	workers := 4
+	assert.NotEqual(t, kafkahash.Hash([]byte("a"))%uint32(workers), kafkahash.Hash([]byte("b"))%uint32(workers), "keys must be handled by different workers")
+	listener := startListener(broker, &SubscribeOption{Topic: "orders", GroupId: "group", Workers: workers}, func(ctx context.Context, key string, data []byte) {
+		if key == "a" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		handled[key] += string(data)
+		order = append(order, key)
+	})
+	defer listener.cancel()
+
+	assert.Eventually(t, func() bool { return broker.committedOffset("orders") == 6 }, 5*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"b", "b", "b"}, order[:3], "key b must not wait for key a")
+	assert.Equal(t, "024", handled["a"])
+	assert.Equal(t, "135", handled["b"])
+	assert.Equal(t, 0, listener.RunningTasks())
+}
@@ -0,0 +1,130 @@
+// Package registry encodes and decodes kafka messages in confluent schema registry wire format,
+// which is magic byte 0, 4 bytes big endian schema id, then avro or protobuf payload
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/odycenter/std-library/app/kafka"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const latestSchemaTTL = 5 * time.Minute
+
+type Schema struct {
+	Id         int    `json:"id"`
+	SchemaType string `json:"schemaType"` // empty means AVRO
+	Schema     string `json:"schema"`
+}
+
+type cachedSchema struct {
+	schema    *Schema
+	fetchedAt time.Time
+}
+
+// Client fetches schemas from registry over http, schemas by id are cached permanently as they are immutable,
+// latest schema of subject is refreshed after 5 minutes to pick up new version
+type Client struct {
+	url      string
+	username string
+	password string
+	http     *http.Client
+	mu       sync.RWMutex
+	ids      map[int]*Schema
+	subjects map[string]cachedSchema
+}
+
+func NewClient(url string) *Client {
+	return &Client{
+		url:      strings.TrimSuffix(url, "/"),
+		http:     &http.Client{Timeout: 10 * time.Second},
+		ids:      make(map[int]*Schema),
+		subjects: make(map[string]cachedSchema),
+	}
+}
+
+func (c *Client) BasicAuth(username, password string) *Client {
+	c.username = username
+	c.password = password
+	return c
+}
+
+// ValueSubject is subject of message value by default TopicNameStrategy
+func ValueSubject(topic string) string {
+	return topic + "-value"
+}
+
+func (c *Client) Schema(ctx context.Context, id int) (*Schema, error) {
+	c.mu.RLock()
+	schema, ok := c.ids[id]
+	c.mu.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	schema = &Schema{}
+	err := c.get(ctx, "/schemas/ids/"+strconv.Itoa(id), schema)
+	if err != nil {
+		return nil, err
+	}
+	schema.Id = id
+	c.mu.Lock()
+	c.ids[id] = schema
+	c.mu.Unlock()
+	return schema, nil
+}
+
+func (c *Client) LatestSchema(ctx context.Context, subject string) (*Schema, error) {
+	c.mu.RLock()
+	cached, ok := c.subjects[subject]
+	c.mu.RUnlock()
+	if ok && time.Since(cached.fetchedAt) < latestSchemaTTL {
+		return cached.schema, nil
+	}
+
+	schema := &Schema{}
+	err := c.get(ctx, "/subjects/"+url.PathEscape(subject)+"/versions/latest", schema)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.subjects[subject] = cachedSchema{schema: schema, fetchedAt: time.Now()}
+	c.ids[schema.Id] = schema
+	c.mu.Unlock()
+	return schema, nil
+}
+
+// get wraps kafka.ErrCodecUnavailable if registry is not reachable or returns server error, so message will be retried
+func (c *Client) get(ctx context.Context, path string, result any) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+path, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if c.username != "" {
+		request.SetBasicAuth(c.username, c.password)
+	}
+	response, err := c.http.Do(request)
+	if err != nil {
+		return fmt.Errorf("%w: failed to call schema registry, path=%s, error=%v", kafka.ErrCodecUnavailable, path, err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("%w: failed to read schema registry response, path=%s, error=%v", kafka.ErrCodecUnavailable, path, err)
+	}
+	if response.StatusCode >= 500 {
+		return fmt.Errorf("%w: schema registry error, path=%s, status=%d, body=%s", kafka.ErrCodecUnavailable, path, response.StatusCode, body)
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("schema not found, path=%s, status=%d, body=%s", path, response.StatusCode, body)
+	}
+	return json.Unmarshal(body, result)
+}
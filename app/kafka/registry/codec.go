@@ -0,0 +1,168 @@
+package registry
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/hamba/avro/v2"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"sync"
+)
+
+const magicByte = 0
+
+func frame(id int, payload []byte) []byte {
+	data := make([]byte, 5, 5+len(payload))
+	data[0] = magicByte
+	binary.BigEndian.PutUint32(data[1:5], uint32(id))
+	return append(data, payload...)
+}
+
+func unframe(data []byte) (int, []byte, error) {
+	if len(data) < 5 || data[0] != magicByte {
+		return 0, nil, errors.New("unknown wire format, magic byte is not found")
+	}
+	return int(binary.BigEndian.Uint32(data[1:5])), data[5:], nil
+}
+
+// AvroCodec encodes message with latest schema of subject, and decodes with writer schema by id,
+// message is struct with avro tags, e.g. `avro:"order_id"`
+type AvroCodec struct {
+	client  *Client
+	subject string
+	mu      sync.RWMutex
+	schemas map[int]avro.Schema
+}
+
+// NewAvroCodec creates codec, subject is only required for encoding, e.g. ValueSubject(topic)
+func NewAvroCodec(client *Client, subject string) *AvroCodec {
+	return &AvroCodec{client: client, subject: subject, schemas: make(map[int]avro.Schema)}
+}
+
+func (c *AvroCodec) Encode(ctx context.Context, message any) ([]byte, error) {
+	schema, err := c.client.LatestSchema(ctx, c.subject)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := c.parse(schema)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := avro.Marshal(parsed, message)
+	if err != nil {
+		return nil, err
+	}
+	return frame(schema.Id, payload), nil
+}
+
+func (c *AvroCodec) Decode(ctx context.Context, data []byte, message any) error {
+	id, payload, err := unframe(data)
+	if err != nil {
+		return err
+	}
+	schema, err := c.client.Schema(ctx, id)
+	if err != nil {
+		return err
+	}
+	parsed, err := c.parse(schema)
+	if err != nil {
+		return err
+	}
+	return avro.Unmarshal(parsed, payload, message)
+}
+
+func (c *AvroCodec) parse(schema *Schema) (avro.Schema, error) {
+	c.mu.RLock()
+	parsed, ok := c.schemas[schema.Id]
+	c.mu.RUnlock()
+	if ok {
+		return parsed, nil
+	}
+	if schema.SchemaType != "" && schema.SchemaType != "AVRO" {
+		return nil, fmt.Errorf("schema is not avro, id=%d, type=%s", schema.Id, schema.SchemaType)
+	}
+	parsed, err := avro.Parse(schema.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse avro schema, id=%d: %w", schema.Id, err)
+	}
+	c.mu.Lock()
+	c.schemas[schema.Id] = parsed
+	c.mu.Unlock()
+	return parsed, nil
+}
+
+// ProtobufCodec encodes proto.Message with schema id of subject and message indexes,
+// decoding only requires the generated type, so registry is not called
+type ProtobufCodec struct {
+	client  *Client
+	subject string
+}
+
+func NewProtobufCodec(client *Client, subject string) *ProtobufCodec {
+	return &ProtobufCodec{client: client, subject: subject}
+}
+
+func (c *ProtobufCodec) Encode(ctx context.Context, message any) ([]byte, error) {
+	protoMessage, ok := message.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("message must be proto.Message, type=%T", message)
+	}
+	schema, err := c.client.LatestSchema(ctx, c.subject)
+	if err != nil {
+		return nil, err
+	}
+	payload := messageIndexes(protoMessage.ProtoReflect().Descriptor())
+	payload, err = proto.MarshalOptions{}.MarshalAppend(payload, protoMessage)
+	if err != nil {
+		return nil, err
+	}
+	return frame(schema.Id, payload), nil
+}
+
+func (c *ProtobufCodec) Decode(_ context.Context, data []byte, message any) error {
+	protoMessage, ok := message.(proto.Message)
+	if !ok {
+		return fmt.Errorf("message must be proto.Message, type=%T", message)
+	}
+	_, payload, err := unframe(data)
+	if err != nil {
+		return err
+	}
+	count, n := binary.Varint(payload)
+	if n <= 0 {
+		return errors.New("invalid protobuf message indexes")
+	}
+	payload = payload[n:]
+	for i := int64(0); i < count; i++ {
+		_, n = binary.Varint(payload)
+		if n <= 0 {
+			return errors.New("invalid protobuf message indexes")
+		}
+		payload = payload[n:]
+	}
+	return proto.Unmarshal(payload, protoMessage)
+}
+
+// messageIndexes is path of message in schema file as zigzag varints prefixed by count, first message is encoded as single 0
+func messageIndexes(descriptor protoreflect.MessageDescriptor) []byte {
+	var indexes []int
+	var current protoreflect.Descriptor = descriptor
+	for {
+		indexes = append([]int{current.Index()}, indexes...)
+		parent, ok := current.Parent().(protoreflect.MessageDescriptor)
+		if !ok {
+			break
+		}
+		current = parent
+	}
+	if len(indexes) == 1 && indexes[0] == 0 {
+		return []byte{0}
+	}
+	data := binary.AppendVarint(nil, int64(len(indexes)))
+	for _, index := range indexes {
+		data = binary.AppendVarint(data, int64(index))
+	}
+	return data
+}
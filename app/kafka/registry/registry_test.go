@@ -0,0 +1,141 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/odycenter/std-library/app/kafka"
+	"github.com/odycenter/std-library/app/web/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+const orderSchema = `{"type":"record","name":"Order","fields":[{"name":"order_id","type":"string"},{"name":"amount","type":"long"}]}`
+
+type order struct {
+	OrderId string `avro:"order_id"`
+	Amount  int64  `avro:"amount"`
+}
+
+type fakeRegistry struct {
+	*httptest.Server
+	requests atomic.Int32
+}
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+	registry := &fakeRegistry{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/subjects/orders-value/versions/latest", func(w http.ResponseWriter, r *http.Request) {
+		registry.requests.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{"subject": "orders-value", "version": 3, "id": 7, "schema": orderSchema})
+	})
+	mux.HandleFunc("/subjects/events-value/versions/latest", func(w http.ResponseWriter, r *http.Request) {
+		registry.requests.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{"subject": "events-value", "version": 1, "id": 8, "schemaType": "PROTOBUF", "schema": "syntax = \"proto3\";"})
+	})
+	mux.HandleFunc("/schemas/ids/7", func(w http.ResponseWriter, r *http.Request) {
+		registry.requests.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{"schema": orderSchema})
+	})
+	mux.HandleFunc("/schemas/ids/9", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	registry.Server = httptest.NewServer(mux)
+	t.Cleanup(registry.Close)
+	return registry
+}
+
+func TestAvroCodec(t *testing.T) {
+	registry := newFakeRegistry(t)
+	ctx := context.Background()
+	encoder := NewAvroCodec(NewClient(registry.URL), ValueSubject("orders"))
+
+	data, err := encoder.Encode(ctx, &order{OrderId: "o-1", Amount: 100})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 0, 7}, data[:5], "magic byte and schema id")
+
+	decoder := NewAvroCodec(NewClient(registry.URL), "")
+	for i := 0; i < 3; i++ {
+		decoded := &order{}
+		require.NoError(t, decoder.Decode(ctx, data, decoded))
+		assert.Equal(t, &order{OrderId: "o-1", Amount: 100}, decoded)
+	}
+	assert.Equal(t, int32(2), registry.requests.Load(), "schemas are cached")
+
+	_, err = encoder.Encode(ctx, &order{OrderId: "o-2"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), registry.requests.Load())
+}
+
+func TestDecodeErrors(t *testing.T) {
+	registry := newFakeRegistry(t)
+	codec := NewAvroCodec(NewClient(registry.URL), "")
+
+	err := codec.Decode(context.Background(), []byte(`{"order_id":"o-1"}`), &order{})
+	assert.ErrorContains(t, err, "magic byte")
+	assert.NotErrorIs(t, err, kafka.ErrCodecUnavailable)
+
+	err = codec.Decode(context.Background(), frame(9, nil), &order{})
+	assert.ErrorIs(t, err, kafka.ErrCodecUnavailable)
+
+	err = codec.Decode(context.Background(), frame(10, nil), &order{})
+	assert.ErrorContains(t, err, "schema not found")
+	assert.NotErrorIs(t, err, kafka.ErrCodecUnavailable)
+
+	registry.Close()
+	err = codec.Decode(context.Background(), frame(11, nil), &order{})
+	assert.ErrorIs(t, err, kafka.ErrCodecUnavailable)
+}
+
+func TestProtobufCodec(t *testing.T) {
+	registry := newFakeRegistry(t)
+	codec := NewProtobufCodec(NewClient(registry.URL), ValueSubject("events"))
+
+	data, err := codec.Encode(context.Background(), wrapperspb.String("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 0, 8}, data[:5])
+
+	decoded := &wrapperspb.StringValue{}
+	require.NoError(t, codec.Decode(context.Background(), data, decoded))
+	assert.Equal(t, "hello", decoded.Value)
+
+	_, err = codec.Encode(context.Background(), &order{})
+	assert.ErrorContains(t, err, "must be proto.Message")
+}
+
+func TestMessageIndexes(t *testing.T) {
+	assert.Equal(t, []byte{0}, messageIndexes((&descriptorpb.FileDescriptorSet{}).ProtoReflect().Descriptor()), "first message")
+	// DescriptorProto is 3rd message of descriptor.proto, ExtensionRange is its 1st nested message, indexes [2, 0] are zigzag encoded
+	assert.Equal(t, []byte{4, 4, 0}, messageIndexes((&descriptorpb.DescriptorProto_ExtensionRange{}).ProtoReflect().Descriptor()))
+}
+
+func TestTypedHandlerWithCodec(t *testing.T) {
+	registry := newFakeRegistry(t)
+	codec := NewAvroCodec(NewClient(registry.URL), ValueSubject("orders"))
+	data, _ := codec.Encode(context.Background(), &order{OrderId: "o-1", Amount: 1})
+
+	var handled *order
+	handler := kafka.NewTypedHandler(func(ctx context.Context, key string, message *order) {
+		handled = message
+	}).Codec(codec)
+	handler.Handle(context.Background(), "o-1", data)
+	assert.Equal(t, "o-1", handled.OrderId)
+
+	assertPanicCode(t, "INVALID_MESSAGE_FORMAT", func() { handler.Handle(context.Background(), "o-1", []byte("{}")) })
+	assertPanicCode(t, "CODEC_UNAVAILABLE", func() { handler.Handle(context.Background(), "o-1", frame(9, nil)) })
+}
+
+func assertPanicCode(t *testing.T, errorCode string, f func()) {
+	defer func() {
+		err := recover()
+		code, ok := err.(errors.Code)
+		require.True(t, ok, "panic with error code, err=%v", err)
+		assert.Equal(t, errorCode, code.ErrorCode())
+	}()
+	f()
+}
@@ -0,0 +1,122 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	internal "github.com/odycenter/std-library/app/internal/module"
+	"github.com/segmentio/kafka-go"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+const forwardRetryInterval = time.Second
+
+// MessageWriter publishes messages to retry and dead letter topics, *kafka.Writer implements it
+type MessageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+func forwardWriter(opt *SubscribeOption) MessageWriter {
+	return &kafka.Writer{
+		Addr:                   kafka.TCP(opt.getBrokers()...),
+		Balancer:               &kafka.Hash{}, // keep key ordering in retry topic
+		RequiredAcks:           kafka.RequireAll,
+		BatchTimeout:           10 * time.Millisecond,
+		AllowAutoTopicCreation: true,
+		Transport:              opt.Security.Transport(),
+	}
+}
+
+// forward publishes failed message to next retry topic or dead letter topic, returns false if message should not be committed
+func (m *MessageListener) forward(record kafka.Message, id string, failure *HandleError) bool {
+	attempt := headerInt(record, HeaderRetryAttempt, 1)
+	topic := m.Opt.deadLetterTopic()
+	message := kafka.Message{Key: record.Key, Value: record.Value}
+	for _, header := range record.Headers {
+		if !forwardHeader(header.Key) {
+			message.Headers = append(message.Headers, header)
+		}
+	}
+	if attempt < m.Opt.maxAttempts() && !invalidMessage(failure) {
+		topic = m.Opt.retryTopic(attempt)
+		retryAt := time.Now().Add(m.Opt.backoff(attempt))
+		message.Headers = append(message.Headers,
+			kafka.Header{Key: HeaderRetryAttempt, Value: []byte(strconv.Itoa(attempt + 1))},
+			kafka.Header{Key: HeaderRetryAt, Value: []byte(strconv.FormatInt(retryAt.UnixMilli(), 10))})
+	}
+	message.Topic = topic
+	message.Headers = append(message.Headers,
+		kafka.Header{Key: HeaderOriginalTopic, Value: []byte(header(record, HeaderOriginalTopic, record.Topic))},
+		kafka.Header{Key: HeaderOriginalPartition, Value: []byte(header(record, HeaderOriginalPartition, strconv.Itoa(record.Partition)))},
+		kafka.Header{Key: HeaderOriginalOffset, Value: []byte(header(record, HeaderOriginalOffset, strconv.FormatInt(record.Offset, 10)))},
+		kafka.Header{Key: HeaderErrorCode, Value: []byte(failure.ErrorCode)},
+		kafka.Header{Key: HeaderErrorMessage, Value: []byte(failure.Message)},
+		kafka.Header{Key: HeaderActionLogId, Value: []byte(id)})
+
+	for {
+		err := m.writer.WriteMessages(m.ctx, message)
+		if err == nil {
+			slog.Warn(fmt.Sprintf("[message-listener] forward failed message, groupId: %s, topic: %s, to: %s, attempt: %d, id: %s, errorCode: %s", m.Opt.GroupId, record.Topic, topic, attempt, id, failure.ErrorCode))
+			return true
+		}
+		slog.Error(fmt.Sprintf("[message-listener] failed to forward message, groupId: %s, topic: %s, to: %s, id: %s, error: %v", m.Opt.GroupId, record.Topic, topic, id, err))
+		if !m.sleep(forwardRetryInterval) {
+			return false
+		}
+	}
+}
+
+// awaitRetry waits until retry_at of message, returns false if listener is stopped
+func (m *MessageListener) awaitRetry(record kafka.Message) bool {
+	retryAt := headerInt(record, HeaderRetryAt, 0)
+	if retryAt == 0 {
+		return true
+	}
+	return m.sleep(time.Until(time.UnixMilli(int64(retryAt))))
+}
+
+// sleep returns false if listener is stopped or server is shutting down
+func (m *MessageListener) sleep(duration time.Duration) bool {
+	deadline := time.Now().Add(duration)
+	for {
+		if internal.IsShutdown() {
+			return false
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return true
+		}
+		select {
+		case <-m.ctx.Done():
+			return false
+		case <-time.After(min(remaining, 100*time.Millisecond)):
+		}
+	}
+}
+
+func forwardHeader(key string) bool {
+	switch key {
+	case HeaderOriginalTopic, HeaderOriginalPartition, HeaderOriginalOffset, HeaderRetryAttempt, HeaderRetryAt, HeaderErrorCode, HeaderErrorMessage, HeaderActionLogId:
+		return true
+	}
+	return false
+}
+
+func header(record kafka.Message, key, defaultValue string) string {
+	for _, h := range record.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return defaultValue
+}
+
+func headerInt(record kafka.Message, key string, defaultValue int) int {
+	value, err := strconv.Atoi(header(record, key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,198 @@
+package kafka
+
+import (
+	"context"
+	"github.com/odycenter/std-library/app/log/consts/logKey"
+	"github.com/odycenter/std-library/app/web/errors"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memoryBroker keeps messages per topic with single partition, used as reader and writer of listener
+type memoryBroker struct {
+	topics    map[string][]kafka.Message
+	committed map[string]int64
+	mu        sync.Mutex
+}
+
+func newMemoryBroker() *memoryBroker {
+	return &memoryBroker{topics: make(map[string][]kafka.Message), committed: make(map[string]int64)}
+}
+
+func (b *memoryBroker) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, msg := range msgs {
+		msg.Offset = int64(len(b.topics[msg.Topic]))
+		msg.Time = time.Now()
+		b.topics[msg.Topic] = append(b.topics[msg.Topic], msg)
+	}
+	return nil
+}
+
+func (b *memoryBroker) Close() error {
+	return nil
+}
+
+func (b *memoryBroker) messages(topic string) []kafka.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]kafka.Message(nil), b.topics[topic]...)
+}
+
+func (b *memoryBroker) committedOffset(topic string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.committed[topic]
+}
+
+// offsets returns high watermark of single partition, timestamp is ignored
+func (b *memoryBroker) offsets(_ context.Context, topic string, _ int64) (map[int]int64, error) {
+	return map[int]int64{0: int64(len(b.messages(topic)))}, nil
+}
+
+func (b *memoryBroker) committedOffsets(_ context.Context, topic string, _ []int) (map[int]int64, error) {
+	return map[int]int64{0: b.committedOffset(topic)}, nil
+}
+
+// reader starts from committed offset, as new member of consumer group
+func (b *memoryBroker) reader(_, topic string, _ *SubscribeOption) MessageReader {
+	return &memoryReader{broker: b, topic: topic, position: int(b.committedOffset(topic))}
+}
+
+type memoryReader struct {
+	broker   *memoryBroker
+	topic    string
+	position int
+}
+
+func (r *memoryReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	for {
+		r.broker.mu.Lock()
+		messages := r.broker.topics[r.topic]
+		if r.position < len(messages) {
+			msg := messages[r.position]
+			msg.HighWaterMark = int64(len(messages))
+			r.position++
+			r.broker.mu.Unlock()
+			return msg, nil
+		}
+		r.broker.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return kafka.Message{}, ctx.Err()
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+func (r *memoryReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	r.broker.mu.Lock()
+	defer r.broker.mu.Unlock()
+	for _, msg := range msgs {
+		r.broker.committed[msg.Topic] = msg.Offset + 1
+	}
+	return nil
+}
+
+func (r *memoryReader) Close() error {
+	return nil
+}
+
+type handlerFunc func(ctx context.Context, key string, data []byte)
+
+func (f handlerFunc) Handle(ctx context.Context, key string, data []byte) {
+	f(ctx, key, data)
+}
+
+func startListener(broker *memoryBroker, opt *SubscribeOption, handler handlerFunc) *MessageListener {
+	listener := &MessageListener{Handler: handler}
+	listener.SetPoolSize(1)
+	listener.Initialize(opt)
+	listener.newReader = broker.reader
+	listener.newWriter = func(*SubscribeOption) MessageWriter {
+		return broker
+	}
+	listener.offsets = broker.offsets
+	listener.committed = broker.committedOffsets
+	listener.Start(context.Background())
+	return listener
+}
+
+func TestRetryThenDeadLetter(t *testing.T) {
+	broker := newMemoryBroker()
+	_ = broker.WriteMessages(context.Background(), kafka.Message{Topic: "orders", Key: []byte("key"), Value: []byte("value"),
+		Headers: []kafka.Header{{Key: logKey.RefId, Value: []byte("ref-id")}}})
+
+	var attempts int32
+	listener := startListener(broker, &SubscribeOption{Topic: "orders", GroupId: "group", Retry: &RetryPolicy{MaxAttempts: 3, Backoff: 10 * time.Millisecond}},
+		func(ctx context.Context, key string, data []byte) {
+			atomic.AddInt32(&attempts, 1)
+			errors.Internal("failed to process", "ORDER_FAILED")
+		})
+	defer listener.cancel()
+
+	assert.Eventually(t, func() bool { return len(broker.messages("orders.dlq")) == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+	assert.Len(t, broker.messages("orders.retry.1"), 1)
+	assert.Len(t, broker.messages("orders.retry.2"), 1)
+	assert.Equal(t, "2", header(broker.messages("orders.retry.1")[0], HeaderRetryAttempt, ""))
+	assert.Equal(t, "3", header(broker.messages("orders.retry.2")[0], HeaderRetryAttempt, ""))
+
+	dlq := broker.messages("orders.dlq")[0]
+	assert.Equal(t, []byte("key"), dlq.Key)
+	assert.Equal(t, []byte("value"), dlq.Value)
+	assert.Equal(t, "orders", header(dlq, HeaderOriginalTopic, ""))
+	assert.Equal(t, "0", header(dlq, HeaderOriginalPartition, ""))
+	assert.Equal(t, "0", header(dlq, HeaderOriginalOffset, ""))
+	assert.Equal(t, "ORDER_FAILED", header(dlq, HeaderErrorCode, ""))
+	assert.NotEmpty(t, header(dlq, HeaderActionLogId, ""))
+	assert.Equal(t, "ref-id", header(dlq, logKey.RefId, ""))
+	assert.Equal(t, "", header(dlq, HeaderRetryAttempt, ""))
+	assert.Eventually(t, func() bool { return broker.committedOffset("orders.retry.2") == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(1), broker.committedOffset("orders"))
+}
+
+func TestRetrySucceeded(t *testing.T) {
+	broker := newMemoryBroker()
+	_ = broker.WriteMessages(context.Background(), kafka.Message{Topic: "orders", Key: []byte("key"), Value: []byte("value")})
+
+	var attempts int32
+	listener := startListener(broker, &SubscribeOption{Topic: "orders", GroupId: "group", Retry: &RetryPolicy{MaxAttempts: 3, Backoff: 10 * time.Millisecond}},
+		func(ctx context.Context, key string, data []byte) {
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				errors.Internal("failed to process", "ORDER_FAILED")
+			}
+		})
+	defer listener.cancel()
+
+	assert.Eventually(t, func() bool { return broker.committedOffset("orders.retry.1") == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+	assert.Empty(t, broker.messages("orders.retry.2"))
+	assert.Empty(t, broker.messages("orders.dlq"))
+}
+
+func TestDeadLetterWithoutRetry(t *testing.T) {
+	broker := newMemoryBroker()
+	_ = broker.WriteMessages(context.Background(), kafka.Message{Topic: "orders", Value: []byte("value")})
+
+	listener := startListener(broker, &SubscribeOption{Topic: "orders", GroupId: "group", DeadLetterTopic: "orders-failed"},
+		func(ctx context.Context, key string, data []byte) {
+			panic("unexpected")
+		})
+	defer listener.cancel()
+
+	assert.Eventually(t, func() bool { return len(broker.messages("orders-failed")) == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "INTERNAL_ERROR", header(broker.messages("orders-failed")[0], HeaderErrorCode, ""))
+}
+
+func TestBackoff(t *testing.T) {
+	opt := &SubscribeOption{Topic: "orders", Retry: &RetryPolicy{MaxAttempts: 4, Backoff: time.Second}}
+	assert.Equal(t, []string{"orders.retry.1", "orders.retry.2", "orders.retry.3"}, opt.retryTopics())
+	assert.Equal(t, time.Second, opt.backoff(1))
+	assert.Equal(t, 4*time.Second, opt.backoff(3))
+}
@@ -0,0 +1,186 @@
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/aws_msk_iam_v2"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	MechanismPlain       = "PLAIN"
+	MechanismScramSHA256 = "SCRAM-SHA-256"
+	MechanismScramSHA512 = "SCRAM-SHA-512"
+	MechanismAWSMSKIAM   = "AWS_MSK_IAM"
+)
+
+// Security configures SASL and TLS of readers, writers and admin client, nil means plaintext without authentication,
+// TLS is enabled if TLS is true, any of CA/client cert is configured, or mechanism is AWS_MSK_IAM
+type Security struct {
+	Mechanism          string
+	Username           string
+	Password           string
+	Region             string // for AWS_MSK_IAM, credentials are loaded from default aws credential chain
+	TLS                bool
+	CAFile             string // PEM bundle, system roots are used if empty
+	CertFile           string // client cert, for mTLS
+	KeyFile            string
+	InsecureSkipVerify bool
+
+	once      sync.Once
+	tls       *tls.Config
+	mechanism sasl.Mechanism
+	err       error
+}
+
+func (s *Security) tlsEnabled() bool {
+	return s.TLS || s.CAFile != "" || s.CertFile != "" || s.Mechanism == MechanismAWSMSKIAM
+}
+
+// Validate loads certs and credentials, it's called by KafkaConfig.Validate to fail fast
+func (s *Security) Validate() error {
+	s.once.Do(func() {
+		s.mechanism, s.err = s.saslMechanism()
+		if s.err == nil && s.tlsEnabled() {
+			s.tls, s.err = s.tlsConfig()
+		}
+	})
+	return s.err
+}
+
+func (s *Security) saslMechanism() (sasl.Mechanism, error) {
+	switch s.Mechanism {
+	case "":
+		return nil, nil
+	case MechanismPlain:
+		if s.Username == "" {
+			return nil, fmt.Errorf("kafka sasl username is required, mechanism=%s", s.Mechanism)
+		}
+		return plain.Mechanism{Username: s.Username, Password: s.Password}, nil
+	case MechanismScramSHA256, MechanismScramSHA512:
+		if s.Username == "" {
+			return nil, fmt.Errorf("kafka sasl username is required, mechanism=%s", s.Mechanism)
+		}
+		algorithm := scram.SHA512
+		if s.Mechanism == MechanismScramSHA256 {
+			algorithm = scram.SHA256
+		}
+		return scram.Mechanism(algorithm, s.Username, s.Password)
+	case MechanismAWSMSKIAM:
+		if s.Region == "" {
+			return nil, fmt.Errorf("kafka sasl region is required, mechanism=%s", s.Mechanism)
+		}
+		cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(s.Region))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load aws config, region=%s: %w", s.Region, err)
+		}
+		return &aws_msk_iam_v2.Mechanism{Signer: v4.NewSigner(), Credentials: cfg.Credentials, Region: s.Region}, nil
+	default:
+		return nil, fmt.Errorf("unsupported kafka sasl mechanism, mechanism=%s", s.Mechanism)
+	}
+}
+
+func (s *Security) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: s.InsecureSkipVerify}
+	if s.CAFile != "" {
+		pem, err := os.ReadFile(s.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kafka ca file, file=%s: %w", s.CAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no cert found in kafka ca file, file=%s", s.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	if s.CertFile != "" || s.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.CertFile, s.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kafka client cert, cert=%s, key=%s: %w", s.CertFile, s.KeyFile, err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+func (s *Security) mustValidate() {
+	if err := s.Validate(); err != nil {
+		panic(err)
+	}
+}
+
+// Dialer is used by readers
+func (s *Security) Dialer(clientID string) *kafka.Dialer {
+	dialer := &kafka.Dialer{
+		Timeout:   10 * time.Second,
+		DualStack: true,
+		ClientID:  clientID,
+	}
+	if s != nil {
+		s.mustValidate()
+		dialer.SASLMechanism = s.mechanism
+		dialer.TLS = s.tls
+	}
+	return dialer
+}
+
+// Transport is used by writers and admin client, returns nil to use kafka.DefaultTransport if security is not configured
+func (s *Security) Transport() kafka.RoundTripper {
+	if s == nil {
+		return nil
+	}
+	s.mustValidate()
+	return &kafka.Transport{
+		DialTimeout: 10 * time.Second,
+		SASL:        s.mechanism,
+		TLS:         s.tls,
+	}
+}
+
+// LibrdkafkaConfig returns properties for confluent client, AWS_MSK_IAM is not supported by librdkafka without token refresh callback
+func (s *Security) LibrdkafkaConfig() (map[string]string, error) {
+	properties := map[string]string{}
+	if s == nil {
+		return properties, nil
+	}
+	protocol := "PLAINTEXT"
+	switch {
+	case s.Mechanism != "" && s.tlsEnabled():
+		protocol = "SASL_SSL"
+	case s.Mechanism != "":
+		protocol = "SASL_PLAINTEXT"
+	case s.tlsEnabled():
+		protocol = "SSL"
+	}
+	properties["security.protocol"] = protocol
+	switch s.Mechanism {
+	case "":
+	case MechanismPlain, MechanismScramSHA256, MechanismScramSHA512:
+		properties["sasl.mechanisms"] = s.Mechanism
+		properties["sasl.username"] = s.Username
+		properties["sasl.password"] = s.Password
+	default:
+		return nil, fmt.Errorf("unsupported kafka sasl mechanism for confluent client, mechanism=%s", s.Mechanism)
+	}
+	if s.CAFile != "" {
+		properties["ssl.ca.location"] = s.CAFile
+	}
+	if s.CertFile != "" {
+		properties["ssl.certificate.location"] = s.CertFile
+		properties["ssl.key.location"] = s.KeyFile
+	}
+	if s.InsecureSkipVerify {
+		properties["enable.ssl.certificate.verification"] = "false"
+	}
+	return properties, nil
+}
@@ -0,0 +1,110 @@
+package kafka
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSecurityNotConfigured(t *testing.T) {
+	var security *Security
+	dialer := security.Dialer("client")
+	assert.Nil(t, dialer.SASLMechanism)
+	assert.Nil(t, dialer.TLS)
+	assert.Equal(t, "client", dialer.ClientID)
+	assert.Nil(t, security.Transport())
+}
+
+func TestSecuritySASL(t *testing.T) {
+	security := &Security{Mechanism: MechanismScramSHA512, Username: "user", Password: "password"}
+	dialer := security.Dialer("client")
+	assert.Equal(t, MechanismScramSHA512, dialer.SASLMechanism.Name())
+	assert.Nil(t, dialer.TLS, "tls is not enabled")
+
+	transport := security.Transport().(*kafka.Transport)
+	assert.Equal(t, MechanismScramSHA512, transport.SASL.Name())
+
+	security = &Security{Mechanism: MechanismPlain, Username: "user", Password: "password", TLS: true}
+	assert.NoError(t, security.Validate())
+	assert.Equal(t, MechanismPlain, security.mechanism.Name())
+	assert.NotNil(t, security.tls)
+}
+
+func TestSecurityValidate(t *testing.T) {
+	assert.ErrorContains(t, (&Security{Mechanism: "GSSAPI"}).Validate(), "unsupported kafka sasl mechanism")
+	assert.ErrorContains(t, (&Security{Mechanism: MechanismScramSHA256}).Validate(), "username is required")
+	assert.ErrorContains(t, (&Security{Mechanism: MechanismAWSMSKIAM}).Validate(), "region is required")
+	assert.ErrorContains(t, (&Security{CAFile: "not-exist.pem"}).Validate(), "failed to read kafka ca file")
+	assert.ErrorContains(t, (&Security{CertFile: "cert.pem"}).Validate(), "failed to load kafka client cert")
+}
+
+func TestSecurityTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir)
+	security := &Security{CAFile: certFile, CertFile: certFile, KeyFile: keyFile}
+	require.NoError(t, security.Validate())
+
+	dialer := security.Dialer("client")
+	assert.NotNil(t, dialer.TLS.RootCAs)
+	assert.Len(t, dialer.TLS.Certificates, 1)
+	assert.False(t, dialer.TLS.InsecureSkipVerify)
+	assert.Same(t, dialer.TLS, security.Transport().(*kafka.Transport).TLS)
+}
+
+func TestLibrdkafkaConfig(t *testing.T) {
+	properties, err := (&Security{Mechanism: MechanismScramSHA512, Username: "user", Password: "password", CAFile: "ca.pem"}).LibrdkafkaConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"security.protocol": "SASL_SSL",
+		"sasl.mechanisms":   "SCRAM-SHA-512",
+		"sasl.username":     "user",
+		"sasl.password":     "password",
+		"ssl.ca.location":   "ca.pem",
+	}, properties)
+
+	properties, _ = (&Security{CertFile: "cert.pem", KeyFile: "key.pem", InsecureSkipVerify: true}).LibrdkafkaConfig()
+	assert.Equal(t, map[string]string{
+		"security.protocol":                   "SSL",
+		"ssl.certificate.location":            "cert.pem",
+		"ssl.key.location":                    "key.pem",
+		"enable.ssl.certificate.verification": "false",
+	}, properties)
+
+	_, err = (&Security{Mechanism: MechanismAWSMSKIAM, Region: "us-east-1"}).LibrdkafkaConfig()
+	assert.Error(t, err)
+}
+
+// writeCert writes self-signed cert, which is used as both ca and client cert
+func writeCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kafka"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
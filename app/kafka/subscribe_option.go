@@ -1,12 +1,62 @@
 package kafka
 
-import "github.com/segmentio/kafka-go"
+import (
+	"github.com/segmentio/kafka-go"
+	"strconv"
+	"time"
+)
 
 type SubscribeOption struct {
-	Brokers     []string
-	GroupId     string
-	Topic       string
-	StartOffset int64
+	Brokers         []string
+	GroupId         string
+	Topic           string
+	StartOffset     int64
+	PoolSize        int
+	Retry           *RetryPolicy // nil means failed message is committed and dropped
+	DeadLetterTopic string       // default is "<topic>.dlq" if retry is enabled
+	MaxBatchSize    int          // only for BulkMessageHandler
+	MaxBatchWait    time.Duration
+	Workers         int          // handle messages of each reader concurrently by key, 0 means sequentially
+	Dedup           *DedupPolicy // only for MessageHandler
+	Security        *Security    // applied to readers, retry writer and offset client
+}
+
+// RetryPolicy republishes failed message to "<topic>.retry.N", N is the attempt already failed,
+// message is handled again after backoff (doubled per attempt), and goes to dead letter topic after MaxAttempts
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+}
+
+// Option configures subscription, used by KafkaConfig.Subscribe
+type Option func(opt *SubscribeOption)
+
+func PoolSize(size int) Option {
+	return func(opt *SubscribeOption) {
+		opt.PoolSize = size
+	}
+}
+
+// Ordered handles messages of each reader with workers concurrently, messages with same key are handled in order by same worker,
+// and offset is committed only after all previous messages of the partition are handled
+func Ordered(workers int) Option {
+	return func(opt *SubscribeOption) {
+		opt.Workers = workers
+	}
+}
+
+// Retry enables retry topics, maxAttempts includes the first attempt on original topic
+func Retry(maxAttempts int, backoff time.Duration) Option {
+	return func(opt *SubscribeOption) {
+		opt.Retry = &RetryPolicy{MaxAttempts: maxAttempts, Backoff: backoff}
+	}
+}
+
+// DeadLetter sends failed message to dead letter topic, after retries are exhausted if Retry is also configured
+func DeadLetter(topic string) Option {
+	return func(opt *SubscribeOption) {
+		opt.DeadLetterTopic = topic
+	}
 }
 
 func (opt *SubscribeOption) getStartOffset() int64 {
@@ -36,3 +86,41 @@ func (opt *SubscribeOption) getBrokers() []string {
 	}
 	return opt.Brokers
 }
+
+func (opt *SubscribeOption) forwardEnabled() bool {
+	return opt.Retry != nil || opt.DeadLetterTopic != ""
+}
+
+func (opt *SubscribeOption) maxAttempts() int {
+	if opt.Retry == nil || opt.Retry.MaxAttempts < 1 {
+		return 1
+	}
+	return opt.Retry.MaxAttempts
+}
+
+func (opt *SubscribeOption) retryTopic(attempt int) string {
+	return opt.getTopic() + ".retry." + strconv.Itoa(attempt)
+}
+
+func (opt *SubscribeOption) retryTopics() []string {
+	var topics []string
+	for attempt := 1; attempt < opt.maxAttempts(); attempt++ {
+		topics = append(topics, opt.retryTopic(attempt))
+	}
+	return topics
+}
+
+func (opt *SubscribeOption) deadLetterTopic() string {
+	if opt.DeadLetterTopic != "" {
+		return opt.DeadLetterTopic
+	}
+	return opt.getTopic() + ".dlq"
+}
+
+// backoff before next attempt, attempt is the number of attempts already failed
+func (opt *SubscribeOption) backoff(attempt int) time.Duration {
+	if opt.Retry == nil || opt.Retry.Backoff <= 0 {
+		return 0
+	}
+	return opt.Retry.Backoff << (attempt - 1)
+}
@@ -0,0 +1,124 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	actionlog "github.com/odycenter/std-library/app/log"
+	"github.com/odycenter/std-library/app/web/errors"
+	reflects "github.com/odycenter/std-library/reflect"
+	"github.com/odycenter/std-library/valid"
+)
+
+// error codes of invalid message, these failures are sent to dead letter topic directly without retry
+const (
+	ErrorCodeInvalidMessageFormat = "INVALID_MESSAGE_FORMAT"
+	ErrorCodeInvalidMessage       = "INVALID_MESSAGE"
+)
+
+// ErrCodecUnavailable is wrapped by codec if it fails due to dependency, e.g. schema registry is not reachable,
+// then message is retried instead of sent to dead letter topic
+var ErrCodecUnavailable = goerrors.New("codec unavailable")
+
+// Codec encodes and decodes typed message, json is used by default, e.g. registry.AvroCodec for schema registry
+type Codec interface {
+	Encode(ctx context.Context, message any) ([]byte, error)
+	Decode(ctx context.Context, data []byte, message any) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(_ context.Context, message any) ([]byte, error) {
+	return json.Marshal(message)
+}
+
+func (jsonCodec) Decode(_ context.Context, data []byte, message any) error {
+	return json.Unmarshal(data, message)
+}
+
+// MessagePublisher publishes message to topic
+type MessagePublisher interface {
+	Publish(ctx context.Context, key string, data []byte) error
+}
+
+// TypedHandler decodes message into T (must be struct) with codec, and validates with valid tags if Validate() is called
+type TypedHandler[T any] struct {
+	handler  func(ctx context.Context, key string, message *T)
+	typeName string
+	validate bool
+	codec    Codec
+}
+
+func NewTypedHandler[T any](handler func(ctx context.Context, key string, message *T)) *TypedHandler[T] {
+	return &TypedHandler[T]{handler: handler, typeName: reflects.StructFullName(new(T)), codec: jsonCodec{}}
+}
+
+func (h *TypedHandler[T]) Codec(codec Codec) *TypedHandler[T] {
+	h.codec = codec
+	return h
+}
+
+func (h *TypedHandler[T]) Validate() *TypedHandler[T] {
+	h.validate = true
+	return h
+}
+
+func (h *TypedHandler[T]) Handle(ctx context.Context, key string, data []byte) {
+	actionlog.Context(&ctx, "message_type", h.typeName)
+	message := new(T)
+	err := h.codec.Decode(ctx, data, message)
+	if goerrors.Is(err, ErrCodecUnavailable) {
+		errors.Internal(fmt.Sprintf("failed to decode message, type=%s, error=%v", h.typeName, err), "CODEC_UNAVAILABLE")
+	}
+	if err != nil {
+		errors.BadRequest(fmt.Sprintf("failed to decode message, type=%s, error=%v", h.typeName, err), ErrorCodeInvalidMessageFormat)
+	}
+	if h.validate {
+		err = valid.Check(message)
+		if err != nil {
+			errors.BadRequest(fmt.Sprintf("invalid message, type=%s, error=%v", h.typeName, err), ErrorCodeInvalidMessage)
+		}
+	}
+	h.handler(ctx, key, message)
+}
+
+// TypedPublisher encodes T with codec, and validates with valid tags if Validate() is called
+type TypedPublisher[T any] struct {
+	publisher MessagePublisher
+	typeName  string
+	validate  bool
+	codec     Codec
+}
+
+func NewTypedPublisher[T any](publisher MessagePublisher) *TypedPublisher[T] {
+	return &TypedPublisher[T]{publisher: publisher, typeName: reflects.StructFullName(new(T)), codec: jsonCodec{}}
+}
+
+func (p *TypedPublisher[T]) Codec(codec Codec) *TypedPublisher[T] {
+	p.codec = codec
+	return p
+}
+
+func (p *TypedPublisher[T]) Validate() *TypedPublisher[T] {
+	p.validate = true
+	return p
+}
+
+func (p *TypedPublisher[T]) Publish(ctx context.Context, key string, message *T) error {
+	if p.validate {
+		err := valid.Check(message)
+		if err != nil {
+			return fmt.Errorf("invalid message, type=%s, error=%w", p.typeName, err)
+		}
+	}
+	data, err := p.codec.Encode(ctx, message)
+	if err != nil {
+		return fmt.Errorf("failed to encode message, type=%s, error=%w", p.typeName, err)
+	}
+	return p.publisher.Publish(ctx, key, data)
+}
+
+func invalidMessage(failure *HandleError) bool {
+	return failure.ErrorCode == ErrorCodeInvalidMessageFormat || failure.ErrorCode == ErrorCodeInvalidMessage
+}
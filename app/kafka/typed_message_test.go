@@ -0,0 +1,60 @@
+package kafka
+
+import (
+	"context"
+	"github.com/odycenter/std-library/app/log/consts/logKey"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type typedMessage struct {
+	Id   string `json:"id" valid:"required"`
+	Name string `json:"name"`
+}
+
+type publisherFunc func(ctx context.Context, key string, data []byte) error
+
+func (f publisherFunc) Publish(ctx context.Context, key string, data []byte) error {
+	return f(ctx, key, data)
+}
+
+func TestTypedHandlerHandle(t *testing.T) {
+	var result *typedMessage
+	handler := NewTypedHandler(func(ctx context.Context, key string, message *typedMessage) {
+		result = message
+	})
+	ctx := context.WithValue(context.Background(), logKey.Context, make(map[string][]any))
+	handler.Handle(ctx, "key", []byte(`{"id":"1","name":"name"}`))
+	assert.Equal(t, &typedMessage{Id: "1", Name: "name"}, result)
+	assert.Equal(t, []any{"kafka.typedMessage"}, ctx.Value(logKey.Context).(map[string][]any)["message_type"])
+}
+
+func TestTypedHandlerInvalidMessage(t *testing.T) {
+	broker := newMemoryBroker()
+	_ = broker.WriteMessages(context.Background(),
+		kafka.Message{Topic: "orders", Value: []byte(`{"id":`)},
+		kafka.Message{Topic: "orders", Value: []byte(`{"name":"name"}`)})
+
+	handler := NewTypedHandler(func(ctx context.Context, key string, message *typedMessage) {}).Validate()
+	listener := startListener(broker, &SubscribeOption{Topic: "orders", GroupId: "group", Retry: &RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}}, handler.Handle)
+	defer listener.cancel()
+
+	assert.Eventually(t, func() bool { return len(broker.messages("orders.dlq")) == 2 }, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, broker.messages("orders.retry.1"), "invalid message must not be retried")
+	assert.Equal(t, ErrorCodeInvalidMessageFormat, header(broker.messages("orders.dlq")[0], HeaderErrorCode, ""))
+	assert.Equal(t, ErrorCodeInvalidMessage, header(broker.messages("orders.dlq")[1], HeaderErrorCode, ""))
+}
+
+func TestTypedPublisherPublish(t *testing.T) {
+	var published []byte
+	publisher := NewTypedPublisher[typedMessage](publisherFunc(func(ctx context.Context, key string, data []byte) error {
+		published = data
+		return nil
+	})).Validate()
+
+	assert.NoError(t, publisher.Publish(context.Background(), "key", &typedMessage{Id: "1"}))
+	assert.JSONEq(t, `{"id":"1","name":""}`, string(published))
+	assert.Error(t, publisher.Publish(context.Background(), "key", &typedMessage{Name: "name"}))
+}
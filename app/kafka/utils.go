@@ -7,6 +7,7 @@ import (
 	actionlog "github.com/odycenter/std-library/app/log"
 	"github.com/odycenter/std-library/app/log/consts/logKey"
 	"github.com/odycenter/std-library/app/log/dto"
+	"github.com/odycenter/std-library/app/web/errors"
 	"github.com/segmentio/kafka-go"
 	"log/slog"
 	"os"
@@ -18,20 +19,20 @@ import (
 var consumerClientIdSequence atomic.Int32
 
 func Reader(clientID string, opt *SubscribeOption) *kafka.Reader {
+	return topicReader(clientID, opt.getTopic(), opt)
+}
+
+func topicReader(clientID, topic string, opt *SubscribeOption) *kafka.Reader {
 	if app.Name == "" { // TODO refactor later
 		panic("app.Name is empty, please set it first!")
 	}
 
-	dialer := &kafka.Dialer{
-		Timeout:   10 * time.Second,
-		DualStack: true,
-		ClientID:  clientID,
-	}
+	dialer := opt.Security.Dialer(clientID)
 	kafkaConfig := kafka.ReaderConfig{
 		Brokers:     opt.getBrokers(),
 		GroupID:     opt.getGroupId(),
 		StartOffset: opt.getStartOffset(),
-		GroupTopics: []string{opt.getTopic()},
+		GroupTopics: []string{topic},
 		MaxBytes:    10e5,             // 10MB
 		MinBytes:    1,                // 1
 		MaxWait:     10 * time.Second, // def:10s
@@ -63,6 +64,31 @@ func CheckConsumerDelay(ctx context.Context, record kafka.Message, actionLog dto
 }
 
 func Handle(clientId, groupId string, record kafka.Message, process func(ctx context.Context, key string, data []byte)) (id string) {
+	id, _ = handle(clientId, groupId, record, process)
+	return
+}
+
+// HandleError is the failure of message handler, recovered from panic
+type HandleError struct {
+	ErrorCode string
+	Message   string
+}
+
+func (e *HandleError) Error() string {
+	return e.ErrorCode + ": " + e.Message
+}
+
+func handleError(r interface{}) *HandleError {
+	if err, ok := r.(errors.Code); ok {
+		return &HandleError{ErrorCode: err.ErrorCode(), Message: err.Error()}
+	}
+	if err, ok := r.(error); ok {
+		return &HandleError{ErrorCode: "INTERNAL_ERROR", Message: err.Error()}
+	}
+	return &HandleError{ErrorCode: "INTERNAL_ERROR", Message: fmt.Sprint(r)}
+}
+
+func handle(clientId, groupId string, record kafka.Message, process func(ctx context.Context, key string, data []byte)) (id string, failure *HandleError) {
 	ctx := context.Background()
 	topic := record.Topic
 	actionLog := actionlog.Begin("topic:"+topic, "message-handler")
@@ -95,6 +121,10 @@ func Handle(clientId, groupId string, record kafka.Message, process func(ctx con
 			clientHostname = string(header.Value)
 			continue
 		}
+		if header.Key == HeaderRetryAttempt {
+			actionLog.PutContext(HeaderRetryAttempt, string(header.Value))
+			continue
+		}
 	}
 	contextMap := make(map[string][]any)
 	if client != "" {
@@ -118,6 +148,7 @@ func Handle(clientId, groupId string, record kafka.Message, process func(ctx con
 
 	defer func() {
 		if err := recover(); err != nil {
+			failure = handleError(err)
 			actionLog.AddStat(statMap)
 
 			actionlog.HandleRecover(err, actionLog, contextMap)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/beego/beego/v2/server/web"
+	"github.com/odycenter/std-library/app/async"
 	"github.com/odycenter/std-library/app/cache"
 	internalcache "github.com/odycenter/std-library/app/internal/cache"
 	internal "github.com/odycenter/std-library/app/internal/module"
@@ -19,22 +20,28 @@ import (
 )
 
 type CacheConfig struct {
-	name            string
-	moduleContext   *Context
-	redisCacheStore *internalcache.RedisCacheStore
-	localCacheStore *internalcache.LocalCacheStore
-	options         *cache.Options
-	maxLocalSize    int
-	caches          map[string]*internalcache.CacheImpl
-	mu              sync.Mutex
+	name               string
+	moduleContext      *Context
+	redisCacheStore    *internalcache.RedisCacheStore
+	localCacheStore    *internalcache.LocalCacheStore
+	tieredCacheStore   *internalcache.TieredCacheStore
+	options            *cache.Options
+	maxLocalSize       int
+	maxLocalExpiration time.Duration
+	codec              cache.Codec
+	compression        int
+	executor           async.IExecutor
+	caches             map[string]*internalcache.CacheImpl
+	mu                 sync.Mutex
 }
 
 func (c *CacheConfig) Initialize(moduleContext *Context, name string) {
 	c.name = name
 	c.moduleContext = moduleContext
 	c.caches = make(map[string]*internalcache.CacheImpl)
-	controller := internal_sys.NewCacheController(c.caches)
+	controller := internal_sys.NewCacheController(c.caches, moduleContext.apiAccessControl)
 	web.Handler("/_sys/cache", controller)
+	web.Handler("/_sys/cache/*", controller)
 	web.Handler("/_sys/cache/*/*", controller)
 }
 
@@ -47,6 +54,12 @@ func (c *CacheConfig) Validate() {
 	if c.maxLocalSize > 0 && c.localCacheStore != nil {
 		c.localCacheStore.MaxSize = c.maxLocalSize
 	}
+	if c.maxLocalExpiration > 0 && c.tieredCacheStore != nil {
+		c.tieredCacheStore.MaxLocalExpiration = c.maxLocalExpiration
+	}
+	if c.redisCacheStore != nil {
+		c.configureCodec()
+	}
 }
 
 func (c *CacheConfig) Local() {
@@ -64,6 +77,21 @@ func (c *CacheConfig) Redis(host string, password ...string) {
 	c.configureRedis(host, password...)
 }
 
+// Tiered uses local cache in front of redis, local copies are invalidated across instances via redis pub/sub
+func (c *CacheConfig) Tiered(host string, password ...string) {
+	if c.localCacheStore != nil || c.redisCacheStore != nil {
+		log.Fatal("cache store is already configured, please configure only once")
+	}
+
+	redisImpl := c.configureRedis(host, password...)
+	c.configureLocalCacheStore()
+	c.tieredCacheStore = internalcache.NewTieredCacheStore(c.localCacheStore, c.redisCacheStore, redisImpl)
+	c.moduleContext.StartupHook.Add(c.tieredCacheStore)
+	c.moduleContext.ShutdownHook.Add(internal.STAGE_2, func(ctx context.Context, timeoutInMs int64) {
+		c.tieredCacheStore.Close()
+	})
+}
+
 func (c *CacheConfig) Options(options *cache.Options) {
 	if c.localCacheStore != nil || c.redisCacheStore != nil {
 		log.Fatalf("cache is already initialized, can not set options! name=" + c.name)
@@ -71,7 +99,7 @@ func (c *CacheConfig) Options(options *cache.Options) {
 	c.options = options
 }
 
-func (c *CacheConfig) Add(obj interface{}, expiration time.Duration) cache.Cache {
+func (c *CacheConfig) Add(obj interface{}, expiration time.Duration, options ...cache.Option) cache.Cache {
 	if c.localCacheStore == nil && c.redisCacheStore == nil {
 		log.Fatal("cache store is not configured, please configure first")
 	}
@@ -93,13 +121,27 @@ func (c *CacheConfig) Add(obj interface{}, expiration time.Duration) cache.Cache
 	var cacheImpl = internalcache.CacheImpl{
 		Expiration: expiration}
 	cacheImpl.TypeName(typeName)
-	if c.redisCacheStore != nil {
+	if c.tieredCacheStore != nil {
+		cacheImpl.CacheStore = c.tieredCacheStore
+	} else if c.redisCacheStore != nil {
 		cacheImpl.CacheStore = c.redisCacheStore
 	} else {
 		cacheImpl.CacheStore = c.localCacheStore
 	}
 	if c.options != nil {
 		cacheImpl.Options = *c.options
+		// codec of store is resolved by configureCodec, only codec set by option of this cache overrides it
+		cacheImpl.Options.Codec = nil
+		cacheImpl.Options.CompressionThreshold = 0
+	}
+	for _, option := range options {
+		option(&cacheImpl.Options)
+	}
+	if cacheImpl.Options.SoftExpiration > 0 {
+		if cacheImpl.Options.SoftExpiration >= expiration {
+			log.Fatalf("soft expiration must be less than expiration, name=%s, softExpiration=%v, expiration=%v", name, cacheImpl.Options.SoftExpiration, expiration)
+		}
+		cacheImpl.Executor = c.refreshExecutor()
 	}
 
 	c.caches[name] = &cacheImpl
@@ -111,7 +153,65 @@ func (c *CacheConfig) MaxLocalSize(size int) {
 	c.maxLocalSize = size
 }
 
-func (c *CacheConfig) configureRedis(host string, password ...string) {
+// MaxLocalExpiration caps how long local copies are kept in tiered cache, default is 1 minute
+func (c *CacheConfig) MaxLocalExpiration(expiration time.Duration) {
+	c.maxLocalExpiration = expiration
+}
+
+// Codec changes serialization of redis cache store, default is JSON
+func (c *CacheConfig) Codec(codec cache.Codec) {
+	c.codec = codec
+}
+
+// Compression enables gzip compression for redis payload larger than threshold in bytes
+func (c *CacheConfig) Compression(threshold int) {
+	c.compression = threshold
+}
+
+func (c *CacheConfig) configureCodec() {
+	codec, compression := c.codec, c.compression
+	if c.options != nil {
+		if codec == nil {
+			codec = c.options.Codec
+		}
+		if compression == 0 {
+			compression = c.options.CompressionThreshold
+		}
+	}
+	c.redisCacheStore.Codec(codec, compression)
+	for name, impl := range c.caches {
+		override := impl.Options.Codec != nil || impl.Options.CompressionThreshold > 0
+		if impl.Options.Codec == nil {
+			impl.Options.Codec = codec
+		}
+		if impl.Options.CompressionThreshold == 0 {
+			impl.Options.CompressionThreshold = compression
+		}
+		if impl.Options.Codec != nil && impl.Options.Codec.ID() == cache.ProtobufCodecID && impl.Options.SoftExpiration > 0 {
+			log.Fatal("protobuf codec does not support stale-while-revalidate, name=" + name)
+		}
+		if !override {
+			continue
+		}
+		if c.tieredCacheStore != nil {
+			impl.CacheStore = c.tieredCacheStore.WithCodec(impl.Options.Codec, impl.Options.CompressionThreshold)
+		} else {
+			impl.CacheStore = c.redisCacheStore.WithCodec(impl.Options.Codec, impl.Options.CompressionThreshold)
+		}
+	}
+}
+
+func (c *CacheConfig) refreshExecutor() async.IExecutor {
+	if c.executor == nil {
+		c.executor = async.New(c.name+"-refresh", 10)
+		c.moduleContext.ShutdownHook.Add(internal.STAGE_2, func(ctx context.Context, timeoutInMs int64) {
+			c.executor.Close()
+		})
+	}
+	return c.executor
+}
+
+func (c *CacheConfig) configureRedis(host string, password ...string) *internalredis.RedisImpl {
 	slog.Info(fmt.Sprintf("create redis cache store, host=%v", host))
 	redisImpl := internalredis.New("redis-cache")
 	hostname := internal.Hostname(host)
@@ -131,13 +231,15 @@ func (c *CacheConfig) configureRedis(host string, password ...string) {
 	redisImpl.Initialize()
 	c.redisCacheStore = &internalcache.RedisCacheStore{}
 	c.redisCacheStore.Initialize(redisImpl)
+	return redisImpl
 }
 
 func (c *CacheConfig) configureLocalCacheStore() {
 	if c.localCacheStore == nil {
 		slog.Info("create local cache store")
-		c.localCacheStore = &internalcache.LocalCacheStore{}
-		// TODO cleanup local cache store
-		// c.localCacheStore.Cleanup()
+		c.localCacheStore = internalcache.NewLocalCacheStore()
+		c.moduleContext.BackgroundTask.ScheduleWithFixedDelay("cleanup-local-cache", func(ctx context.Context) {
+			c.localCacheStore.Cleanup()
+		}, 30*time.Minute)
 	}
 }
@@ -87,6 +87,10 @@ func (c *Common) Kafka(name ...string) *KafkaConfig {
 	return c.ModuleContext.Config(configName("kafka", name...), func() Config { return &KafkaConfig{} }).(*KafkaConfig)
 }
 
+func (c *Common) Outbox(name ...string) *OutboxConfig {
+	return c.ModuleContext.Config(configName("outbox", name...), func() Config { return &OutboxConfig{} }).(*OutboxConfig)
+}
+
 func (c *Common) Schedule() *SchedulerConfig {
 	return c.ModuleContext.Config("scheduler", func() Config { return &SchedulerConfig{} }).(*SchedulerConfig)
 }
@@ -22,6 +22,7 @@ func (c *HTTPConfig) Initialize(moduleContext *Context, name string) {
 	c.moduleContext = moduleContext
 
 	c.apiController = internalSys.NewAPIController()
+	c.apiController.AccessControl = moduleContext.apiAccessControl
 	web.Handler("/_sys/api/*", c.apiController)
 }
 
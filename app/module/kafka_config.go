@@ -3,15 +3,21 @@ package module
 import (
 	"context"
 	"fmt"
+	"github.com/beego/beego/v2/server/web"
 	app "github.com/odycenter/std-library/app/conf"
 	internal "github.com/odycenter/std-library/app/internal/module"
+	"github.com/odycenter/std-library/app/internal/web/sys"
 	"github.com/odycenter/std-library/app/kafka"
+	actionlog "github.com/odycenter/std-library/app/log"
 	"log"
 	"log/slog"
 	"runtime"
 	"sync"
+	"time"
 )
 
+const kafkaDedupPurgeInterval = time.Hour
+
 type KafkaConfig struct {
 	name          string
 	groupId       string
@@ -22,6 +28,9 @@ type KafkaConfig struct {
 	m             map[string]*kafka.MessageListener
 	mu            sync.RWMutex
 	handlerAdded  bool
+	producer      *kafka.Producer
+	security      *kafka.Security
+	dedupPurges   map[*kafka.DBDedupStore]bool
 }
 
 func (c *KafkaConfig) Initialize(moduleContext *Context, name string) {
@@ -34,6 +43,12 @@ func (c *KafkaConfig) Initialize(moduleContext *Context, name string) {
 	}
 	slog.Info(fmt.Sprintf("kafka consumer default poolSize: %d", c.poolSize))
 	c.m = make(map[string]*kafka.MessageListener)
+	c.dedupPurges = make(map[*kafka.DBDedupStore]bool)
+	if moduleContext.kafkaController == nil {
+		moduleContext.kafkaController = internal_sys.NewKafkaController(moduleContext.apiAccessControl)
+		web.Handler("/_sys/kafka", moduleContext.kafkaController)
+		web.Handler("/_sys/kafka/*", moduleContext.kafkaController)
+	}
 	c.moduleContext.StartupHook.Add(c)
 	c.moduleContext.ShutdownHook.Add(internal.STAGE_1, func(ctx context.Context, timeoutInMs int64) {
 		c.stop(ctx, timeoutInMs)
@@ -41,12 +56,17 @@ func (c *KafkaConfig) Initialize(moduleContext *Context, name string) {
 }
 
 func (c *KafkaConfig) Validate() {
-	if !c.handlerAdded {
-		log.Fatalf("kafka is configured, but no handler added, please remove unnecessary config, name=" + c.name)
+	if !c.handlerAdded && c.producer == nil {
+		log.Fatalf("kafka is configured, but no handler or publisher added, please remove unnecessary config, name=" + c.name)
 	}
 	if len(c.uri) == 0 {
 		log.Fatalf("kafka uri is not configured, name=" + c.name)
 	}
+	if c.security != nil {
+		if err := c.security.Validate(); err != nil {
+			log.Fatalf("invalid kafka security config, name=%s, error=%v", c.name, err)
+		}
+	}
 }
 
 func (c *KafkaConfig) Uri(uri string) {
@@ -70,16 +90,107 @@ func (c *KafkaConfig) DefaultPoolSize(size int) {
 	c.poolSize = size
 }
 
+// SASL configures authentication, mechanism is kafka.MechanismPlain, kafka.MechanismScramSHA256 or kafka.MechanismScramSHA512,
+// use AWSIAM for AWS_MSK_IAM
+func (c *KafkaConfig) SASL(mechanism, username, password string) {
+	security := c.securityConfig()
+	security.Mechanism = mechanism
+	security.Username = username
+	security.Password = password
+}
+
+// AWSIAM authenticates by AWS_MSK_IAM with default aws credential chain, TLS is enabled as required by MSK
+func (c *KafkaConfig) AWSIAM(region string) {
+	security := c.securityConfig()
+	security.Mechanism = kafka.MechanismAWSMSKIAM
+	security.Region = region
+}
+
+// TLS enables TLS, caFile is PEM bundle to verify brokers, system roots are used if empty
+func (c *KafkaConfig) TLS(caFile string) {
+	security := c.securityConfig()
+	security.TLS = true
+	security.CAFile = caFile
+}
+
+// ClientCert enables mutual TLS
+func (c *KafkaConfig) ClientCert(certFile, keyFile string) {
+	security := c.securityConfig()
+	security.CertFile = certFile
+	security.KeyFile = keyFile
+}
+
+func (c *KafkaConfig) securityConfig() *kafka.Security {
+	if c.producer != nil {
+		log.Fatalf("kafka producer is already created, please configure security first, name=" + c.name)
+	}
+	if c.security == nil {
+		c.security = &kafka.Security{}
+	}
+	return c.security
+}
+
+// Publisher publishes message to topic, all publishers share one producer, which is closed after all tasks completed during shutdown
+func (c *KafkaConfig) Publisher(topic string) *kafka.Publisher {
+	return c.sharedProducer().Publisher(topic)
+}
+
+func (c *KafkaConfig) sharedProducer() *kafka.Producer {
+	if len(c.uri) == 0 {
+		log.Fatalf("kafka uri is not configured, please configure first, name=" + c.name)
+	}
+	if c.producer == nil {
+		slog.Info(fmt.Sprintf("create kafka producer, uri=%s, name=%s", c.uriString, c.name))
+		c.producer = kafka.NewProducer(c.uri, c.security)
+		c.moduleContext.ShutdownHook.Add(internal.STAGE_4, func(ctx context.Context, timeoutInMs int64) {
+			c.producer.Close(ctx)
+		})
+	}
+	return c.producer
+}
+
 // Subscribe that use app.Name as groupID.
 func (c *KafkaConfig) Subscribe(topic string, handler kafka.MessageHandler, poolSize ...int) {
-	c.subscribe(topic, kafka.FirstOffset, handler, poolSize...)
+	c.subscribe(topic, kafka.FirstOffset, handler, poolSizeOptions(poolSize)...)
 }
 
 func (c *KafkaConfig) SubscribeByOffset(topic string, startOffset int64, handler kafka.MessageHandler, poolSize ...int) {
-	c.subscribe(topic, startOffset, handler, poolSize...)
+	c.subscribe(topic, startOffset, handler, poolSizeOptions(poolSize)...)
+}
+
+// SubscribeWithOptions is Subscribe with options, e.g. kafka.PoolSize(4), kafka.Retry(3, time.Second), kafka.DeadLetter("topic.dlq")
+func (c *KafkaConfig) SubscribeWithOptions(topic string, handler kafka.MessageHandler, options ...kafka.Option) {
+	c.subscribe(topic, kafka.FirstOffset, handler, options...)
 }
 
-func (c *KafkaConfig) subscribe(topic string, startOffset int64, handler kafka.MessageHandler, poolSize ...int) {
+func (c *KafkaConfig) SubscribeByOffsetWithOptions(topic string, startOffset int64, handler kafka.MessageHandler, options ...kafka.Option) {
+	c.subscribe(topic, startOffset, handler, options...)
+}
+
+func poolSizeOptions(poolSize []int) []kafka.Option {
+	if len(poolSize) > 0 && poolSize[0] > 0 {
+		return []kafka.Option{kafka.PoolSize(poolSize[0])}
+	}
+	return nil
+}
+
+// SubscribeBulk handles up to maxSize messages in one batch, batch is handled once maxWait elapsed since first message fetched
+func (c *KafkaConfig) SubscribeBulk(topic string, handler kafka.BulkMessageHandler, maxSize int, maxWait time.Duration, options ...kafka.Option) {
+	if maxSize <= 0 || maxWait <= 0 {
+		log.Fatalf("bulk maxSize and maxWait must be greater than 0, topic: %s", topic)
+	}
+	batch := func(opt *kafka.SubscribeOption) {
+		opt.MaxBatchSize = maxSize
+		opt.MaxBatchWait = maxWait
+	}
+	c.subscribeListener(topic, kafka.FirstOffset, &kafka.MessageListener{BulkHandler: handler}, append([]kafka.Option{batch}, options...)...)
+}
+
+func (c *KafkaConfig) subscribe(topic string, startOffset int64, handler kafka.MessageHandler, options ...kafka.Option) {
+	c.subscribeListener(topic, startOffset, &kafka.MessageListener{Handler: handler}, options...)
+}
+
+func (c *KafkaConfig) subscribeListener(topic string, startOffset int64, listener *kafka.MessageListener, options ...kafka.Option) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -88,29 +199,84 @@ func (c *KafkaConfig) subscribe(topic string, startOffset int64, handler kafka.M
 		log.Fatalf("topic is already subscribed, topic: %s ", topic)
 	}
 
-	listener := kafka.MessageListener{
-		Handler: handler,
-	}
-	if len(poolSize) > 0 && poolSize[0] > 0 {
-		listener.SetPoolSize(poolSize[0])
-	} else {
-		listener.SetPoolSize(c.poolSize)
-	}
 	opt := &kafka.SubscribeOption{
 		Topic:       topic,
 		StartOffset: startOffset,
 	}
+	for _, option := range options {
+		option(opt)
+	}
+	if opt.Retry != nil && opt.Retry.MaxAttempts < 1 {
+		log.Fatalf("retry max attempts must be greater than 0, topic: %s", topic)
+	}
+
+	if opt.PoolSize > 0 {
+		listener.SetPoolSize(opt.PoolSize)
+	} else {
+		listener.SetPoolSize(c.poolSize)
+	}
 	listener.Initialize(opt)
+	c.purgeDedup(opt)
 
-	c.m[opt.Topic] = &listener
+	c.m[opt.Topic] = listener
+	c.moduleContext.kafkaController.Add(listener)
 	c.handlerAdded = true
 }
 
+// purgeDedup deletes expired ids of db dedup store periodically, store shared by topics is purged by one task
+func (c *KafkaConfig) purgeDedup(opt *kafka.SubscribeOption) {
+	if opt.Dedup == nil {
+		return
+	}
+	store, ok := opt.Dedup.Store.(*kafka.DBDedupStore)
+	if !ok || c.dedupPurges[store] {
+		return
+	}
+	c.dedupPurges[store] = true
+	c.moduleContext.BackgroundTask.ScheduleWithFixedDelay("kafka-dedup-purge", func(ctx context.Context) {
+		deleted, err := store.Purge(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("failed to purge kafka dedup ids, error=%v", err))
+			return
+		}
+		actionlog.Stat(&ctx, "kafka_dedup_purged", float64(deleted))
+	}, kafkaDedupPurgeInterval)
+}
+
+// Pause stops fetching messages of topic without leaving consumer group, only affects current instance
+func (c *KafkaConfig) Pause(topic string) {
+	c.listener(topic).Pause()
+}
+
+func (c *KafkaConfig) Resume(topic string) {
+	c.listener(topic).Resume()
+}
+
+// ResetOffset commits group offsets of topic to kafka.FirstOffset or kafka.LastOffset
+func (c *KafkaConfig) ResetOffset(ctx context.Context, topic string, offset int64) (map[int]int64, error) {
+	return c.listener(topic).ResetOffset(ctx, offset)
+}
+
+func (c *KafkaConfig) ResetOffsetByTime(ctx context.Context, topic string, at time.Time) (map[int]int64, error) {
+	return c.listener(topic).ResetOffsetByTime(ctx, at)
+}
+
+func (c *KafkaConfig) listener(topic string) *kafka.MessageListener {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	listener, ok := c.m[topic]
+	if !ok {
+		panic("topic is not subscribed, topic: " + topic)
+	}
+	return listener
+}
+
 func (c *KafkaConfig) start(ctx context.Context) {
 	c.mu.RLock()
 	for _, listener := range c.m {
 		listener.Opt.GroupId = c.groupId
 		listener.Opt.Brokers = c.uri
+		listener.Opt.Security = c.security
 		listener.Start(ctx)
 	}
 	c.mu.RUnlock()
@@ -6,6 +6,7 @@ import (
 	internalLog "github.com/odycenter/std-library/app/internal/log"
 	internal "github.com/odycenter/std-library/app/internal/module"
 	internalWeb "github.com/odycenter/std-library/app/internal/web"
+	internalHttp "github.com/odycenter/std-library/app/internal/web/http"
 	"github.com/odycenter/std-library/app/internal/web/sys"
 	"github.com/odycenter/std-library/app/property"
 	"log/slog"
@@ -17,12 +18,16 @@ type Context struct {
 	StartupHook       *internal.StartupHook
 	ShutdownHook      *internal.ShutdownHook
 	Probe             *internal.ReadinessProbe
+	BackgroundTask    *internal.BackgroundTaskExecutor
 	PropertyManager   *property.Manager
 	propertyValidator *property.Validator
 	configs           sync.Map // map[string]Config
 	listenPorts       sync.Map // map[int]bool
 	httpServer        *internalWeb.HTTPServer
 	httpConfigAdded   bool
+	apiAccessControl  *internalHttp.IPv4AccessControl // shared by /_sys/api and management controllers, configured by HTTPConfig.AllowAPI
+	kafkaController   *internal_sys.KafkaController   // shared by all kafka configs
+	outboxController  *internal_sys.OutboxController  // shared by all outbox configs
 }
 
 func (m *Context) Initialize() {
@@ -35,9 +40,11 @@ func (m *Context) Initialize() {
 	m.ShutdownHook = &internal.ShutdownHook{}
 	m.Probe = &internal.ReadinessProbe{}
 	m.ShutdownHook.Initialize()
+	m.BackgroundTask = m.createBackgroundTaskExecutor()
 	m.PropertyManager = property.NewManager()
 	m.propertyValidator = property.NewValidator()
 	m.httpServer = m.createHTTPServer()
+	m.apiAccessControl = &internalHttp.IPv4AccessControl{}
 
 	web.Handler("/_sys/property", internal_sys.NewPropertyController(m.PropertyManager))
 }
@@ -59,6 +66,20 @@ func (m *Context) createHTTPServer() *internalWeb.HTTPServer {
 	return httpServer
 }
 
+func (m *Context) createBackgroundTaskExecutor() *internal.BackgroundTaskExecutor {
+	executor := internal.NewBackgroundTaskExecutor()
+
+	m.StartupHook.Add(executor)
+	m.ShutdownHook.Add(internal.STAGE_2, func(ctx context.Context, timeoutInMs int64) {
+		executor.Shutdown()
+	})
+	m.ShutdownHook.Add(internal.STAGE_3, func(ctx context.Context, timeoutInMs int64) {
+		executor.AwaitTermination(ctx, timeoutInMs)
+	})
+
+	return executor
+}
+
 func (m *Context) AddListenPort(port int) {
 	if _, exists := m.listenPorts.Load(port); exists {
 		slog.Warn("Port already added", "port", port)
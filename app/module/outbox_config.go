@@ -0,0 +1,102 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"github.com/beego/beego/v2/server/web"
+	"github.com/odycenter/std-library/app/internal/web/sys"
+	"github.com/odycenter/std-library/app/outbox"
+	"github.com/odycenter/std-library/dbase"
+	"log"
+	"log/slog"
+	"time"
+)
+
+const (
+	outboxRelayInterval = time.Second
+	outboxPurgeInterval = time.Hour
+)
+
+// OutboxConfig publishes messages saved within db transaction, relay runs as background task,
+// create table by outbox.DDL before enable
+type OutboxConfig struct {
+	name          string
+	moduleContext *Context
+	relay         *outbox.Relay
+	alias         string
+	table         string
+	dialect       dbase.Dialect
+	retention     time.Duration
+}
+
+func (c *OutboxConfig) Initialize(moduleContext *Context, name string) {
+	c.name = name
+	c.moduleContext = moduleContext
+	c.table = "outbox"
+	c.retention = 7 * 24 * time.Hour
+	c.relay = outbox.NewRelay(name, nil, nil)
+	if moduleContext.outboxController == nil {
+		moduleContext.outboxController = internal_sys.NewOutboxController(moduleContext.apiAccessControl)
+		web.Handler("/_sys/outbox", moduleContext.outboxController)
+	}
+	moduleContext.outboxController.Add(c.relay)
+	moduleContext.BackgroundTask.ScheduleWithFixedDelay("outbox-relay", func(ctx context.Context) {
+		c.relay.Run(ctx)
+	}, outboxRelayInterval)
+	moduleContext.BackgroundTask.ScheduleWithFixedDelay("outbox-purge", func(ctx context.Context) {
+		c.relay.Purge(ctx, c.retention)
+	}, outboxPurgeInterval)
+}
+
+func (c *OutboxConfig) Validate() {
+	if c.relay.Store == nil {
+		log.Fatalf("outbox db is not configured, name=" + c.name)
+	}
+	if c.relay.Publisher == nil {
+		log.Fatalf("outbox kafka is not configured, name=" + c.name)
+	}
+}
+
+// DB uses orm alias of db config, the business transaction must be on same db, dialect is mysql by default
+func (c *OutboxConfig) DB(alias string, dialect ...dbase.Dialect) *OutboxConfig {
+	if c.relay.Store != nil {
+		log.Fatalf("outbox db is already configured, name=%s, alias=%s, previous=%s", c.name, alias, c.alias)
+	}
+	c.alias = alias
+	if len(dialect) > 0 {
+		c.dialect = dialect[0]
+	}
+	c.relay.Store = outbox.NewDBStore(c.alias, c.table, c.dialect)
+	return c
+}
+
+// Table must be configured before DB
+func (c *OutboxConfig) Table(table string) *OutboxConfig {
+	if c.relay.Store != nil {
+		log.Fatalf("outbox db is already configured, can not change table, name=%s", c.name)
+	}
+	c.table = table
+	return c
+}
+
+// Kafka uses shared producer of kafka config to relay messages
+func (c *OutboxConfig) Kafka(kafka *KafkaConfig) *OutboxConfig {
+	c.relay.Publisher = kafka.sharedProducer()
+	return c
+}
+
+// Retention of sent messages, default is 7 days
+func (c *OutboxConfig) Retention(retention time.Duration) *OutboxConfig {
+	c.retention = retention
+	return c
+}
+
+// Publisher saves message with given transaction, e.g.
+// db.Tx(func(ctx context.Context, tx *dbase.TxOrm) error { ...; return publisher.Publish(ctx, tx, key, data) })
+func (c *OutboxConfig) Publisher(topic string) *outbox.Publisher {
+	if c.relay.Store == nil {
+		log.Fatalf("outbox db is not configured, please configure first, name=" + c.name)
+	}
+	slog.Debug(fmt.Sprintf("create outbox publisher, name=%s, topic=%s", c.name, topic))
+	return outbox.NewPublisher(topic, c.relay.Store)
+}
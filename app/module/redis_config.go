@@ -5,6 +5,7 @@ import (
 	"fmt"
 	internal "github.com/odycenter/std-library/app/internal/module"
 	internalredis "github.com/odycenter/std-library/app/internal/redis"
+	"github.com/odycenter/std-library/app/redis"
 	"log"
 	"log/slog"
 	"time"
@@ -81,6 +82,11 @@ func (c *RedisConfig) DB(db int) {
 	c.redis.DB(db)
 }
 
+// Client is available after startup, or ForceEarlyStart
+func (c *RedisConfig) Client() redis.Redis {
+	return c.redis
+}
+
 func (c *RedisConfig) PoolSize(minSize, maxSize int) {
 	if c.redis.Initialized() {
 		log.Fatalf("redis is already initialized, can not set poolSize! name=" + c.name)
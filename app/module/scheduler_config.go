@@ -8,6 +8,7 @@ import (
 	"github.com/odycenter/std-library/app/internal/web/sys"
 	"github.com/odycenter/std-library/app/scheduler"
 	reflects "github.com/odycenter/std-library/reflect"
+	"time"
 )
 
 type SchedulerConfig struct {
@@ -18,6 +19,14 @@ type SchedulerConfig struct {
 func (c *SchedulerConfig) Initialize(moduleContext *Context, name string) {
 	c.scheduler = internalscheduler.New()
 	c.scheduler.PanicOnAnyAddError(false)
+	c.scheduler.Overrides(func(name string) (string, bool) {
+		if moduleContext.PropertyManager == nil { // registered at runtime after startup
+			return "", false
+		}
+		spec := moduleContext.Property("sys.job." + name + ".spec")
+		disabled := moduleContext.Property("sys.job."+name+".enabled") == "false"
+		return spec, disabled
+	})
 	moduleContext.StartupHook.Add(c.scheduler)
 	moduleContext.ShutdownHook.Add(internal.STAGE_1, func(ctx context.Context, timeoutInMs int64) {
 		c.scheduler.AwaitTermination(ctx, timeoutInMs)
@@ -44,11 +53,58 @@ func (c *SchedulerConfig) AddJobWithName(spec, action string, job scheduler.Job,
 	return c.scheduler.AddWithName(spec, action, job, panicOnAddError...)
 }
 
-func (c *SchedulerConfig) AddDisallowConcurrent(spec string, job scheduler.Job, panicOnAddError ...bool) (scheduler.JobID, error) {
+// AddWithOptions adds job with timeout, retries and misfire policy, e.g. scheduler.Options{DisallowConcurrent: true, Retries: 3}
+func (c *SchedulerConfig) AddWithOptions(spec string, job scheduler.Job, options scheduler.Options) (scheduler.JobID, error) {
 	action := reflects.StructName(job)
-	return c.scheduler.AddDisallowConcurrentByName(spec, action, internalscheduler.DisallowConcurrent(job), panicOnAddError...)
+	return c.scheduler.AddWithOptions(spec, action, job, options)
+}
+
+func (c *SchedulerConfig) AddJobWithOptions(spec, action string, job scheduler.Job, options scheduler.Options) (scheduler.JobID, error) {
+	return c.scheduler.AddWithOptions(spec, action, job, options)
+}
+
+func (c *SchedulerConfig) AddFuncJobWithOptions(spec, action string, process func(ctx context.Context), options scheduler.Options) (scheduler.JobID, error) {
+	return c.scheduler.AddFuncWithOptions(spec, action, process, options)
+}
+
+// AddDisallowConcurrent is shortcut of AddWithOptions with scheduler.Options{DisallowConcurrent: true}
+func (c *SchedulerConfig) AddDisallowConcurrent(spec string, job scheduler.Job, panicOnAddError ...bool) (scheduler.JobID, error) {
+	return c.AddWithOptions(spec, job, scheduler.Options{
+		DisallowConcurrent: true,
+		PanicOnAddError:    len(panicOnAddError) > 0 && panicOnAddError[0],
+	})
+}
+
+// FireTimeStore persists fire times to handle fire times missed during downtime by misfire policy,
+// e.g. scheduler.NewRedisFireTimeStore(redis, "scheduler:fire:"+app+":")
+func (c *SchedulerConfig) FireTimeStore(store scheduler.FireTimeStore) {
+	c.scheduler.FireTimeStore(store)
+}
+
+// Cluster enables leader election by lease, e.g. scheduler.NewRedisLease(redis, "scheduler:leader:"+app),
+// only leader fires jobs, so jobs run once per cluster
+func (c *SchedulerConfig) Cluster(lease scheduler.Lease) {
+	c.scheduler.Cluster(lease)
 }
 
 func (c *SchedulerConfig) SetPanicOnAnyAddError(panicOnAnyAddError bool) {
 	c.scheduler.PanicOnAnyAddError(panicOnAnyAddError)
 }
+
+// DelayedJobs enables persistent one-off jobs, e.g. scheduler.NewRedisDelayedJobStore(redis, "{delayed-job:"+app+"}:")
+func (c *SchedulerConfig) DelayedJobs(store scheduler.DelayedJobStore) {
+	c.scheduler.DelayedJobStore(store)
+}
+
+func (c *SchedulerConfig) AddDelayedHandler(name string, handler func(ctx context.Context, payload string)) {
+	c.scheduler.AddDelayedHandler(name, handler)
+}
+
+// ScheduleAt runs handler once at runAt with payload, job survives restarts and runs on one node of cluster
+func (c *SchedulerConfig) ScheduleAt(ctx context.Context, handler string, runAt time.Time, payload string) (string, error) {
+	return c.scheduler.ScheduleAt(ctx, handler, runAt, payload)
+}
+
+func (c *SchedulerConfig) ScheduleAfter(ctx context.Context, handler string, delay time.Duration, payload string) (string, error) {
+	return c.scheduler.ScheduleAt(ctx, handler, time.Now().Add(delay), payload)
+}
@@ -3,6 +3,7 @@ package module
 import (
 	"embed"
 	app "github.com/odycenter/std-library/app/conf"
+	"github.com/odycenter/std-library/app/kafka"
 	"github.com/odycenter/std-library/logs"
 	"log"
 	"log/slog"
@@ -120,6 +121,20 @@ func (m *SystemModule) configureKafka() {
 	if kafkaUri != "" {
 		m.Kafka().Uri(kafkaUri)
 	}
+	mechanism := m.Property("sys.kafka.sasl.mechanism")
+	if mechanism == kafka.MechanismAWSMSKIAM {
+		m.Kafka().AWSIAM(m.RequiredProperty("sys.kafka.sasl.region"))
+	} else if mechanism != "" {
+		m.Kafka().SASL(mechanism, m.RequiredProperty("sys.kafka.sasl.username"), m.Property("sys.kafka.sasl.password"))
+	}
+	caFile := m.Property("sys.kafka.tls.ca")
+	if caFile != "" || strings.ToLower(m.Property("sys.kafka.tls")) == "true" {
+		m.Kafka().TLS(caFile)
+	}
+	certFile := m.Property("sys.kafka.tls.cert")
+	if certFile != "" {
+		m.Kafka().ClientCert(certFile, m.RequiredProperty("sys.kafka.tls.key"))
+	}
 }
 
 func (m *SystemModule) configureRedis() {
@@ -0,0 +1,180 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/odycenter/std-library/dbase"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DDL returns idempotent statements to create outbox table, execute them one by one,
+// times are stored as unix millis to be independent of db time zone
+func DDL(dialect dbase.Dialect, table string) []string {
+	if dialect == dbase.Postgres {
+		return []string{
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+    id BIGSERIAL PRIMARY KEY,
+    topic VARCHAR(255) NOT NULL,
+    message_key VARCHAR(255) NOT NULL,
+    message_value BYTEA NOT NULL,
+    ref_id VARCHAR(50) NOT NULL,
+    sent SMALLINT NOT NULL DEFAULT 0,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at BIGINT NOT NULL,
+    error_message VARCHAR(1000) NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL
+)`, table),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS ix_%[1]s_sent ON %[1]s (sent, id)`, table),
+		}
+	}
+	return []string{fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    topic VARCHAR(255) NOT NULL,
+    message_key VARCHAR(255) NOT NULL,
+    message_value MEDIUMBLOB NOT NULL,
+    ref_id VARCHAR(50) NOT NULL,
+    sent TINYINT NOT NULL DEFAULT 0,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at BIGINT NOT NULL,
+    error_message VARCHAR(1000) NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY (id),
+    INDEX ix_%[1]s_sent (sent, id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`, table)}
+}
+
+// DBStore saves messages to table of db registered by alias, the relay transaction uses read committed isolation,
+// to not block inserts of business transactions by gap locks
+type DBStore struct {
+	alias   string
+	table   string
+	dialect dbase.Dialect
+}
+
+func NewDBStore(alias, table string, dialect dbase.Dialect) *DBStore {
+	return &DBStore{alias: alias, table: table, dialect: dialect}
+}
+
+func (s *DBStore) Insert(_ context.Context, tx *dbase.TxOrm, message *Message) error {
+	query := "INSERT INTO " + s.table + " (topic, message_key, message_value, ref_id, sent, attempts, next_attempt_at, error_message, created_at) VALUES (?, ?, ?, ?, 0, 0, ?, '', ?)"
+	_, err := tx.Raw(query, message.Topic, message.Key, message.Value, message.RefId, message.NextAttemptAt.UnixMilli(), message.CreatedAt.UnixMilli()).Exec()
+	if err != nil {
+		return fmt.Errorf("failed to insert outbox message, table=%s, topic=%s: %w", s.table, message.Topic, err)
+	}
+	return nil
+}
+
+func (s *DBStore) Process(ctx context.Context, limit int, fn func(ctx context.Context, messages []*Message)) error {
+	db, err := orm.GetDB(s.alias)
+	if err != nil {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	messages, err := s.pending(ctx, tx, limit)
+	if err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	fn(ctx, messages)
+	for _, message := range messages {
+		if message.Sent {
+			_, err = tx.ExecContext(ctx, s.rebind("UPDATE "+s.table+" SET sent = 1 WHERE id = ?"), message.Id)
+		} else if message.ErrorMessage != "" {
+			_, err = tx.ExecContext(ctx, s.rebind("UPDATE "+s.table+" SET attempts = ?, next_attempt_at = ?, error_message = ? WHERE id = ?"),
+				message.Attempts, message.NextAttemptAt.UnixMilli(), message.ErrorMessage, message.Id)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// pending locks due messages with FOR UPDATE, relays of other instances wait until this batch is committed,
+// message is skipped if earlier message with same key is waiting for retry, to keep the order within partition
+func (s *DBStore) pending(ctx context.Context, tx *sql.Tx, limit int) ([]*Message, error) {
+	now := time.Now().UnixMilli()
+	query := "SELECT id, topic, message_key, message_value, ref_id, attempts, next_attempt_at, error_message, created_at FROM " + s.table + " m" +
+		" WHERE sent = 0 AND next_attempt_at <= ?" +
+		" AND (message_key = '' OR NOT EXISTS (SELECT 1 FROM " + s.table + " w WHERE w.sent = 0 AND w.topic = m.topic AND w.message_key = m.message_key AND w.id < m.id AND w.next_attempt_at > ?))" +
+		" ORDER BY id LIMIT ? FOR UPDATE"
+	rows, err := tx.QueryContext(ctx, s.rebind(query), now, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var messages []*Message
+	for rows.Next() {
+		message := &Message{}
+		var nextAttemptAt, createdAt int64
+		err = rows.Scan(&message.Id, &message.Topic, &message.Key, &message.Value, &message.RefId, &message.Attempts, &nextAttemptAt, &message.ErrorMessage, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+		message.NextAttemptAt = time.UnixMilli(nextAttemptAt)
+		message.CreatedAt = time.UnixMilli(createdAt)
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+func (s *DBStore) Backlog(ctx context.Context) (Backlog, error) {
+	var backlog Backlog
+	db, err := orm.GetDB(s.alias)
+	if err != nil {
+		return backlog, err
+	}
+	var retrying, oldest sql.NullInt64
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*), SUM(CASE WHEN attempts > 0 THEN 1 ELSE 0 END), MIN(created_at) FROM "+s.table+" WHERE sent = 0").
+		Scan(&backlog.Pending, &retrying, &oldest)
+	if err != nil {
+		return backlog, err
+	}
+	backlog.Retrying = retrying.Int64
+	if oldest.Valid {
+		createdAt := time.UnixMilli(oldest.Int64)
+		backlog.OldestCreatedAt = &createdAt
+	}
+	return backlog, nil
+}
+
+func (s *DBStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	db, err := orm.GetDB(s.alias)
+	if err != nil {
+		return 0, err
+	}
+	result, err := db.ExecContext(ctx, s.rebind("DELETE FROM "+s.table+" WHERE sent = 1 AND created_at < ?"), before.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// rebind converts ? placeholders to $n for postgres
+func (s *DBStore) rebind(query string) string {
+	if s.dialect != dbase.Postgres {
+		return query
+	}
+	var builder strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			builder.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		builder.WriteRune(c)
+	}
+	return builder.String()
+}
@@ -0,0 +1,64 @@
+package outbox
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/odycenter/std-library/dbase"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDDL(t *testing.T) {
+	mysql := DDL(dbase.MySQL, "order_outbox")
+	assert.Len(t, mysql, 1)
+	assert.Contains(t, mysql[0], "CREATE TABLE IF NOT EXISTS order_outbox")
+	assert.Contains(t, mysql[0], "AUTO_INCREMENT")
+	assert.Contains(t, mysql[0], "INDEX ix_order_outbox_sent (sent, id)")
+
+	postgres := DDL(dbase.Postgres, "order_outbox")
+	assert.Len(t, postgres, 2)
+	assert.Contains(t, postgres[0], "BIGSERIAL")
+	assert.Equal(t, "CREATE INDEX IF NOT EXISTS ix_order_outbox_sent ON order_outbox (sent, id)", postgres[1])
+}
+
+func TestRebind(t *testing.T) {
+	query := "UPDATE outbox SET attempts = ?, next_attempt_at = ? WHERE id = ?"
+	assert.Equal(t, query, NewDBStore("default", "outbox", dbase.MySQL).rebind(query))
+	assert.Equal(t, "UPDATE outbox SET attempts = $1, next_attempt_at = $2 WHERE id = $3", NewDBStore("default", "outbox", dbase.Postgres).rebind(query))
+}
+
+func TestDBStoreProcess(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	mock.ExpectQuery("SELECT current_setting").WillReturnRows(sqlmock.NewRows([]string{"timezone"}).AddRow("UTC"))
+	alias := "outbox-" + strconv.FormatInt(time.Now().UnixNano(), 10) // orm aliases can't be unregistered
+	assert.NoError(t, orm.AddAliasWthDB(alias, "postgres", db))
+	store := NewDBStore(alias, "outbox", dbase.Postgres)
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM outbox m WHERE sent = 0 AND next_attempt_at <= $1 AND (message_key = '' OR NOT EXISTS (SELECT 1 FROM outbox w WHERE w.sent = 0 AND w.topic = m.topic AND w.message_key = m.message_key AND w.id < m.id AND w.next_attempt_at > $2)) ORDER BY id LIMIT $3 FOR UPDATE")).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "topic", "message_key", "message_value", "ref_id", "attempts", "next_attempt_at", "error_message", "created_at"}).
+			AddRow(1, "orders", "a", []byte("a1"), "ref-1", 0, now.UnixMilli(), "", now.UnixMilli()).
+			AddRow(2, "orders", "b", []byte("b1"), "ref-2", 0, now.UnixMilli(), "", now.UnixMilli()))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE outbox SET sent = 1 WHERE id = $1")).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE outbox SET attempts = $1, next_attempt_at = $2, error_message = $3 WHERE id = $4")).
+		WithArgs(1, now.Add(time.Second).UnixMilli(), "broker not available", 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = store.Process(context.Background(), 10, func(_ context.Context, messages []*Message) {
+		assert.Len(t, messages, 2)
+		assert.Equal(t, "a1", string(messages[0].Value))
+		messages[0].Sent = true
+		messages[1].Attempts = 1
+		messages[1].NextAttemptAt = now.Add(time.Second)
+		messages[1].ErrorMessage = "broker not available"
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
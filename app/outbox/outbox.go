@@ -0,0 +1,62 @@
+// Package outbox saves messages within the business db transaction, and relays them to kafka after commit,
+// so messages are neither lost nor published for rolled back changes
+package outbox
+
+import (
+	"context"
+	actionlog "github.com/odycenter/std-library/app/log"
+	"github.com/odycenter/std-library/dbase"
+	"time"
+)
+
+type Message struct {
+	Id            int64
+	Topic         string
+	Key           string
+	Value         []byte
+	RefId         string
+	Sent          bool
+	Attempts      int
+	NextAttemptAt time.Time
+	ErrorMessage  string
+	CreatedAt     time.Time
+}
+
+type Backlog struct {
+	Pending         int64
+	Retrying        int64 // pending messages failed at least once
+	OldestCreatedAt *time.Time
+}
+
+// Store persists messages, DBStore is the implementation for mysql and postgres
+type Store interface {
+	// Insert saves message within the business transaction
+	Insert(ctx context.Context, tx *dbase.TxOrm, message *Message) error
+	// Process locks up to limit due messages ordered by id, skips message if earlier message with same key is not due, fn updates Sent/Attempts/NextAttemptAt/ErrorMessage of messages, which are saved after fn returns
+	Process(ctx context.Context, limit int, fn func(ctx context.Context, messages []*Message)) error
+	Backlog(ctx context.Context) (Backlog, error)
+	// Purge deletes sent messages created before given time
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Publisher saves message to outbox with the transaction, the relay publishes it to topic after commit
+type Publisher struct {
+	topic string
+	store Store
+}
+
+func NewPublisher(topic string, store Store) *Publisher {
+	return &Publisher{topic: topic, store: store}
+}
+
+func (p *Publisher) Publish(ctx context.Context, tx *dbase.TxOrm, key string, data []byte) error {
+	now := time.Now()
+	return p.store.Insert(ctx, tx, &Message{
+		Topic:         p.topic,
+		Key:           key,
+		Value:         data,
+		RefId:         actionlog.GetId(&ctx),
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	})
+}
@@ -0,0 +1,141 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	actionlog "github.com/odycenter/std-library/app/log"
+	"github.com/odycenter/std-library/app/web/metric"
+	"log/slog"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultBatchSize  = 100
+	defaultBackoff    = time.Second
+	defaultMaxBackoff = 5 * time.Minute
+	maxErrorLength    = 1000
+)
+
+// MessagePublisher is implemented by kafka.Producer
+type MessagePublisher interface {
+	PublishWithRefId(ctx context.Context, topic, refId, key string, data []byte) error
+}
+
+// Relay publishes pending messages in id order, failed message is retried with exponential backoff,
+// later messages with same key wait until it's published, to keep the order within partition
+type Relay struct {
+	Name       string
+	Store      Store
+	Publisher  MessagePublisher
+	BatchSize  int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	now        func() time.Time
+}
+
+func NewRelay(name string, store Store, publisher MessagePublisher) *Relay {
+	return &Relay{
+		Name:       name,
+		Store:      store,
+		Publisher:  publisher,
+		BatchSize:  defaultBatchSize,
+		Backoff:    defaultBackoff,
+		MaxBackoff: defaultMaxBackoff,
+		now:        time.Now,
+	}
+}
+
+// Run relays until no full batch is published, then updates backlog metrics
+func (r *Relay) Run(ctx context.Context) {
+	for {
+		published := 0
+		err := r.Store.Process(ctx, r.BatchSize, func(ctx context.Context, messages []*Message) {
+			published = r.publish(ctx, messages)
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("failed to relay outbox messages, name=%s, error=%v", r.Name, err))
+			break
+		}
+		if published < r.BatchSize {
+			break
+		}
+	}
+	r.updateBacklog(ctx)
+}
+
+func (r *Relay) publish(ctx context.Context, messages []*Message) int {
+	published := 0
+	blocked := make(map[string]bool) // topic/key of message waiting for retry
+	now := r.now()
+	for _, message := range messages {
+		orderKey := message.Topic + "/" + message.Key
+		if message.Key != "" && blocked[orderKey] {
+			continue
+		}
+		if message.NextAttemptAt.After(now) {
+			blocked[orderKey] = true
+			continue
+		}
+		err := r.Publisher.PublishWithRefId(ctx, message.Topic, message.RefId, message.Key, message.Value)
+		if err != nil {
+			message.Attempts++
+			message.NextAttemptAt = now.Add(r.backoff(message.Attempts))
+			message.ErrorMessage = truncate(err.Error())
+			blocked[orderKey] = true
+			metric.OutboxPublishFailed.WithLabelValues(r.Name, message.Topic).Inc()
+			slog.WarnContext(ctx, fmt.Sprintf("failed to publish outbox message, id=%d, topic=%s, key=%s, attempts=%d, nextAttemptAt=%s, error=%v",
+				message.Id, message.Topic, message.Key, message.Attempts, message.NextAttemptAt.Format(time.RFC3339), err))
+			continue
+		}
+		message.Sent = true
+		published++
+		metric.OutboxPublished.WithLabelValues(r.Name, message.Topic).Inc()
+	}
+	actionlog.Stat(&ctx, "outbox_published", actionlog.GetStat(&ctx, "outbox_published")+float64(published))
+	return published
+}
+
+func (r *Relay) backoff(attempts int) time.Duration {
+	backoff := r.Backoff << (attempts - 1)
+	if backoff <= 0 || backoff > r.MaxBackoff { // overflow or exceeds max
+		return r.MaxBackoff
+	}
+	return backoff
+}
+
+func (r *Relay) updateBacklog(ctx context.Context) {
+	backlog, err := r.Store.Backlog(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, fmt.Sprintf("failed to query outbox backlog, name=%s, error=%v", r.Name, err))
+		return
+	}
+	metric.OutboxPendingMessages.WithLabelValues(r.Name).Set(float64(backlog.Pending))
+	var age float64
+	if backlog.OldestCreatedAt != nil {
+		age = r.now().Sub(*backlog.OldestCreatedAt).Seconds()
+	}
+	metric.OutboxOldestPendingSeconds.WithLabelValues(r.Name).Set(age)
+}
+
+// Purge deletes sent messages older than retention
+func (r *Relay) Purge(ctx context.Context, retention time.Duration) {
+	deleted, err := r.Store.Purge(ctx, r.now().Add(-retention))
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("failed to purge outbox messages, name=%s, error=%v", r.Name, err))
+		return
+	}
+	actionlog.Stat(&ctx, "outbox_purged", float64(deleted))
+}
+
+// truncate cuts message to maxErrorLength bytes on rune boundary
+func truncate(message string) string {
+	if len(message) <= maxErrorLength {
+		return message
+	}
+	end := maxErrorLength
+	for end > 0 && !utf8.RuneStart(message[end]) {
+		end--
+	}
+	return message[:end]
+}
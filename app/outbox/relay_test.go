@@ -0,0 +1,176 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"github.com/odycenter/std-library/dbase"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// memoryStore is stand-in of DBStore
+type memoryStore struct {
+	messages []*Message
+	now      func() time.Time
+}
+
+func (s *memoryStore) Insert(_ context.Context, _ *dbase.TxOrm, message *Message) error {
+	message.Id = int64(len(s.messages) + 1)
+	s.messages = append(s.messages, message)
+	return nil
+}
+
+func (s *memoryStore) Process(ctx context.Context, limit int, fn func(ctx context.Context, messages []*Message)) error {
+	now := time.Now()
+	if s.now != nil {
+		now = s.now()
+	}
+	var pending []*Message
+	waiting := make(map[string]bool) // topic/key of message not due
+	for _, message := range s.messages {
+		if message.Sent || len(pending) >= limit {
+			continue
+		}
+		orderKey := message.Topic + "/" + message.Key
+		if message.NextAttemptAt.After(now) {
+			waiting[orderKey] = true
+			continue
+		}
+		if message.Key != "" && waiting[orderKey] {
+			continue
+		}
+		copied := *message
+		pending = append(pending, &copied)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	fn(ctx, pending)
+	for _, message := range pending {
+		s.messages[message.Id-1] = message
+	}
+	return nil
+}
+
+func (s *memoryStore) Backlog(_ context.Context) (Backlog, error) {
+	var backlog Backlog
+	for _, message := range s.messages {
+		if message.Sent {
+			continue
+		}
+		backlog.Pending++
+		if message.Attempts > 0 {
+			backlog.Retrying++
+		}
+		if backlog.OldestCreatedAt == nil || message.CreatedAt.Before(*backlog.OldestCreatedAt) {
+			createdAt := message.CreatedAt
+			backlog.OldestCreatedAt = &createdAt
+		}
+	}
+	return backlog, nil
+}
+
+func (s *memoryStore) Purge(_ context.Context, before time.Time) (int64, error) {
+	var deleted int64
+	for _, message := range s.messages {
+		if message.Sent && message.CreatedAt.Before(before) && message.Value != nil {
+			message.Value = nil
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+type publishedMessage struct {
+	topic string
+	key   string
+	value string
+}
+
+type memoryPublisher struct {
+	published []publishedMessage
+	failed    map[string]bool // value to fail
+}
+
+func (p *memoryPublisher) PublishWithRefId(_ context.Context, topic, _, key string, data []byte) error {
+	if p.failed[string(data)] {
+		return errors.New("broker not available")
+	}
+	p.published = append(p.published, publishedMessage{topic, key, string(data)})
+	return nil
+}
+
+func TestRelayPublishesInOrder(t *testing.T) {
+	store := &memoryStore{}
+	publisher := &memoryPublisher{}
+	ctx := context.Background()
+	orders := NewPublisher("orders", store)
+	for _, value := range []string{"1", "2", "3"} {
+		assert.NoError(t, orders.Publish(ctx, nil, "order-1", []byte(value)))
+	}
+
+	relay := NewRelay("outbox", store, publisher)
+	relay.BatchSize = 2
+	relay.Run(ctx)
+
+	assert.Equal(t, []publishedMessage{{"orders", "order-1", "1"}, {"orders", "order-1", "2"}, {"orders", "order-1", "3"}}, publisher.published)
+	backlog, _ := store.Backlog(ctx)
+	assert.Equal(t, int64(0), backlog.Pending)
+}
+
+func TestRelayRetryKeepsKeyOrder(t *testing.T) {
+	store := &memoryStore{}
+	publisher := &memoryPublisher{failed: map[string]bool{"a1": true}}
+	ctx := context.Background()
+	orders := NewPublisher("orders", store)
+	_ = orders.Publish(ctx, nil, "a", []byte("a1"))
+	_ = orders.Publish(ctx, nil, "b", []byte("b1"))
+	_ = orders.Publish(ctx, nil, "a", []byte("a2"))
+
+	now := time.Now()
+	relay := NewRelay("outbox", store, publisher)
+	relay.now = func() time.Time { return now }
+	store.now = relay.now
+	relay.Run(ctx)
+
+	assert.Equal(t, []publishedMessage{{"orders", "b", "b1"}}, publisher.published, "a2 must wait for a1")
+	failed := store.messages[0]
+	assert.Equal(t, 1, failed.Attempts)
+	assert.Equal(t, now.Add(time.Second), failed.NextAttemptAt)
+	assert.Equal(t, "broker not available", failed.ErrorMessage)
+	backlog, _ := store.Backlog(ctx)
+	assert.Equal(t, Backlog{Pending: 2, Retrying: 1, OldestCreatedAt: &store.messages[0].CreatedAt}, backlog)
+
+	delete(publisher.failed, "a1")
+	relay.Run(ctx)
+	assert.Len(t, publisher.published, 1, "a1 is not published before backoff")
+
+	now = now.Add(time.Second)
+	relay.Run(ctx)
+	var values []string
+	for _, message := range publisher.published {
+		values = append(values, message.value)
+	}
+	assert.Equal(t, []string{"b1", "a1", "a2"}, values)
+}
+
+func TestRelayBackoff(t *testing.T) {
+	relay := NewRelay("outbox", nil, nil)
+	relay.MaxBackoff = 10 * time.Second
+	var backoffs []time.Duration
+	for _, attempts := range []int{1, 2, 3, 4, 5, 100} {
+		backoffs = append(backoffs, relay.backoff(attempts))
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}, backoffs)
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "error", truncate("error"))
+	message := strings.Repeat("a", maxErrorLength-1) + "錯誤"
+	truncated := truncate(message)
+	assert.Equal(t, strings.Repeat("a", maxErrorLength-1), truncated)
+	assert.True(t, utf8.ValidString(truncated))
+}
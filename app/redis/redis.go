@@ -14,3 +14,16 @@ type Redis interface {
 	MultiSet(ctx context.Context, values map[string]interface{}, expiration time.Duration) error
 	Del(ctx context.Context, keys ...string) (int64, error)
 }
+
+// Scripter and ConditionalSetter are kept out of Redis to not break existing implementations,
+// client of RedisConfig implements both
+
+// Scripter runs lua script
+type Scripter interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// ConditionalSetter sets key only if it does not exist
+type ConditionalSetter interface {
+	SetNX(ctx context.Context, key string, value string, expiration time.Duration) (bool, error)
+}
@@ -0,0 +1,124 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// Calendar excludes fire times, e.g. market holidays, t is in time zone of job
+type Calendar interface {
+	Excluded(t time.Time) bool
+}
+
+// DateCalendar excludes whole days, dates are in format 2006-01-02
+type DateCalendar struct {
+	dates map[string]struct{}
+}
+
+func NewDateCalendar(dates ...string) *DateCalendar {
+	calendar := &DateCalendar{dates: make(map[string]struct{}, len(dates))}
+	for _, date := range dates {
+		if _, err := time.Parse(dateLayout, date); err != nil {
+			panic(fmt.Sprintf("invalid calendar date, date=%s, layout=%s", date, dateLayout))
+		}
+		calendar.dates[date] = struct{}{}
+	}
+	return calendar
+}
+
+func (c *DateCalendar) Excluded(t time.Time) bool {
+	_, ok := c.dates[t.Format(dateLayout)]
+	return ok
+}
+
+// WeeklyCalendar excludes days of week, e.g. NewWeeklyCalendar(time.Saturday, time.Sunday)
+type WeeklyCalendar struct {
+	mask [7]bool
+}
+
+func NewWeeklyCalendar(excluded ...time.Weekday) *WeeklyCalendar {
+	calendar := &WeeklyCalendar{}
+	for _, day := range excluded {
+		calendar.mask[day] = true
+	}
+	return calendar
+}
+
+func (c *WeeklyCalendar) Excluded(t time.Time) bool {
+	return c.mask[t.Weekday()]
+}
+
+// LoaderCalendar excludes dates loaded by function, e.g. from db or config service, dates are reloaded in background after refresh interval,
+// previous dates are served while reloading and kept if reload failed, only first load blocks
+type LoaderCalendar struct {
+	load     func(ctx context.Context) ([]string, error)
+	refresh  time.Duration
+	mu       sync.Mutex
+	dates    *DateCalendar
+	loadedAt time.Time
+	loading  bool
+}
+
+func NewLoaderCalendar(load func(ctx context.Context) ([]string, error), refresh time.Duration) *LoaderCalendar {
+	return &LoaderCalendar{load: load, refresh: refresh}
+}
+
+// Excluded is called by cron to calculate next fire time, so loader is never called with lock held
+func (c *LoaderCalendar) Excluded(t time.Time) bool {
+	c.mu.Lock()
+	dates := c.dates
+	reload := !c.loading && (dates == nil || time.Since(c.loadedAt) >= c.refresh)
+	if reload {
+		c.loading = true
+	}
+	c.mu.Unlock()
+
+	if reload && dates == nil {
+		dates = c.reload()
+	} else if reload {
+		go c.reload()
+	}
+	return dates != nil && dates.Excluded(t)
+}
+
+func (c *LoaderCalendar) reload() *DateCalendar {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	dates, err := c.load(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loading = false
+	c.loadedAt = time.Now()
+	if err != nil {
+		slog.Warn(fmt.Sprintf("failed to load calendar dates, error: %v", err))
+		return c.dates
+	}
+	calendar := &DateCalendar{dates: make(map[string]struct{}, len(dates))}
+	for _, date := range dates {
+		calendar.dates[date] = struct{}{}
+	}
+	c.dates = calendar
+	return calendar
+}
+
+// Calendars excludes fire time if any of calendars excludes it
+func Calendars(calendars ...Calendar) Calendar {
+	return calendarList(calendars)
+}
+
+type calendarList []Calendar
+
+func (l calendarList) Excluded(t time.Time) bool {
+	for _, calendar := range l {
+		if calendar.Excluded(t) {
+			return true
+		}
+	}
+	return false
+}
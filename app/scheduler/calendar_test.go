@@ -0,0 +1,84 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDateCalendar(t *testing.T) {
+	calendar := NewDateCalendar("2024-10-01", "2024-10-02")
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	assert.True(t, calendar.Excluded(time.Date(2024, 10, 1, 0, 5, 0, 0, shanghai)))
+	assert.True(t, calendar.Excluded(time.Date(2024, 10, 2, 23, 59, 0, 0, shanghai)))
+	assert.False(t, calendar.Excluded(time.Date(2024, 10, 3, 0, 5, 0, 0, shanghai)))
+
+	assert.Panics(t, func() { NewDateCalendar("2024/10/01") })
+}
+
+func TestWeeklyCalendar(t *testing.T) {
+	calendar := NewWeeklyCalendar(time.Saturday, time.Sunday)
+	assert.True(t, calendar.Excluded(time.Date(2024, 10, 5, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, calendar.Excluded(time.Date(2024, 10, 6, 0, 0, 0, 0, time.UTC)))
+	assert.False(t, calendar.Excluded(time.Date(2024, 10, 7, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestLoaderCalendar(t *testing.T) {
+	var loads int32
+	var failed atomic.Bool
+	calendar := NewLoaderCalendar(func(ctx context.Context) ([]string, error) {
+		atomic.AddInt32(&loads, 1)
+		if failed.Load() {
+			return nil, errors.New("db unavailable")
+		}
+		return []string{"2024-10-01"}, nil
+	}, time.Hour)
+	holiday := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	assert.True(t, calendar.Excluded(holiday))
+	assert.True(t, calendar.Excluded(holiday))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads), "dates are cached")
+
+	calendar.mu.Lock()
+	calendar.loadedAt = time.Now().Add(-2 * time.Hour)
+	calendar.mu.Unlock()
+	failed.Store(true)
+	assert.True(t, calendar.Excluded(holiday))
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&loads) == 2 }, time.Second, 10*time.Millisecond)
+	assert.True(t, calendar.Excluded(holiday), "previous dates are kept if reload failed")
+}
+
+func TestLoaderCalendarServesStaleWhileLoading(t *testing.T) {
+	release := make(chan struct{})
+	var loads int32
+	calendar := NewLoaderCalendar(func(ctx context.Context) ([]string, error) {
+		if atomic.AddInt32(&loads, 1) > 1 {
+			<-release
+			return []string{"2024-10-02"}, nil
+		}
+		return []string{"2024-10-01"}, nil
+	}, time.Hour)
+	holiday := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	assert.True(t, calendar.Excluded(holiday))
+
+	calendar.mu.Lock()
+	calendar.loadedAt = time.Now().Add(-2 * time.Hour)
+	calendar.mu.Unlock()
+	assert.True(t, calendar.Excluded(holiday), "stale dates are served while loader is blocked")
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&loads) == 2 }, time.Second, time.Millisecond)
+	assert.True(t, calendar.Excluded(holiday))
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&loads), "only one reload at a time")
+
+	close(release)
+	assert.Eventually(t, func() bool { return calendar.Excluded(holiday.AddDate(0, 0, 1)) }, time.Second, 10*time.Millisecond)
+}
+
+func TestCalendars(t *testing.T) {
+	calendar := Calendars(NewDateCalendar("2024-10-01"), NewWeeklyCalendar(time.Sunday))
+	assert.True(t, calendar.Excluded(time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, calendar.Excluded(time.Date(2024, 10, 6, 0, 0, 0, 0, time.UTC)))
+	assert.False(t, calendar.Excluded(time.Date(2024, 10, 7, 0, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,233 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/odycenter/std-library/app/redis"
+	"strconv"
+	"time"
+)
+
+// DelayedJob runs once at RunAt by handler registered with name Handler
+type DelayedJob struct {
+	Id        string
+	Handler   string
+	Payload   string
+	RunAt     time.Time
+	RefId     string // id of action which scheduled job
+	CreatedAt time.Time
+	Attempts  int  // failed attempts
+	Parked    bool // parked job is not claimed until rescheduled, e.g. retries exhausted or handler not found
+}
+
+// DelayedJobStore stores delayed jobs durably, Claim must be atomic, so due job is claimed by only one instance,
+// claimed job is hidden until timeout, and claimed again if it's not completed, e.g. instance crashed during execution
+type DelayedJobStore interface {
+	// Schedule saves job, existing job with same id is replaced, e.g. to retry
+	Schedule(ctx context.Context, job *DelayedJob) error
+	Claim(ctx context.Context, now time.Time, limit int, timeout time.Duration) ([]*DelayedJob, error)
+	// Extend keeps claimed job hidden until given time, returns false if job is not in queue, e.g. canceled
+	Extend(ctx context.Context, id string, until time.Time) (bool, error)
+	Complete(ctx context.Context, id string) error
+	// Park saves job and moves it out of queue, it's listed and can be rescheduled or canceled
+	Park(ctx context.Context, job *DelayedJob) error
+	List(ctx context.Context, limit int) ([]*DelayedJob, error)
+	Cancel(ctx context.Context, id string) (bool, error)
+	// Reschedule changes run at of job in queue, or moves parked job back to queue with attempts reset
+	Reschedule(ctx context.Context, id string, runAt time.Time) (bool, error)
+}
+
+const (
+	scheduleScript = `redis.call('HSET', KEYS[2], ARGV[1], ARGV[3])
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+return 1`
+	claimScript = `local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'WITHSCORES', 'LIMIT', 0, ARGV[2])
+local result = {}
+for i = 1, #due, 2 do
+	local job = redis.call('HGET', KEYS[2], due[i])
+	if job then
+		redis.call('ZADD', KEYS[1], ARGV[3], due[i])
+		table.insert(result, job)
+		table.insert(result, due[i + 1])
+	else
+		redis.call('ZREM', KEYS[1], due[i])
+	end
+end
+return result`
+	listScript = `local jobs = redis.call('ZRANGE', KEYS[1], 0, ARGV[1], 'WITHSCORES')
+local result = {}
+for i = 1, #jobs, 2 do
+	local job = redis.call('HGET', KEYS[2], jobs[i])
+	if job then
+		table.insert(result, job)
+		table.insert(result, jobs[i + 1])
+	end
+end
+return result`
+	extendScript = `if redis.call('ZSCORE', KEYS[1], ARGV[1]) then
+	redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+	return 1
+end
+return 0`
+	parkScript = `redis.call('HSET', KEYS[2], ARGV[1], ARGV[3])
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('ZADD', KEYS[3], ARGV[2], ARGV[1])
+return 1`
+	removeScript = `redis.call('HDEL', KEYS[2], ARGV[1])
+return redis.call('ZREM', KEYS[1], ARGV[1]) + redis.call('ZREM', KEYS[3], ARGV[1])`
+	rescheduleScript = `if redis.call('ZSCORE', KEYS[1], ARGV[1]) then
+	redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+	return 1
+end
+if redis.call('ZREM', KEYS[3], ARGV[1]) == 1 then
+	local job = redis.call('HGET', KEYS[2], ARGV[1])
+	if job then
+		local data = cjson.decode(job)
+		data.attempts = nil
+		redis.call('HSET', KEYS[2], ARGV[1], cjson.encode(data))
+	end
+	redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+	return 1
+end
+return 0`
+)
+
+// delayedJobData is stored in hash, run at is score of sorted set
+type delayedJobData struct {
+	Id        string `json:"id"`
+	Handler   string `json:"handler"`
+	Payload   string `json:"payload"`
+	RefId     string `json:"ref_id,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+	Attempts  int    `json:"attempts,omitempty"`
+}
+
+// RedisDelayedJobStore stores jobs in sorted set scored by run at in unix millis, and job data in hash,
+// parked jobs are moved to another sorted set
+type RedisDelayedJobStore struct {
+	redis redis.Scripter
+	keys  []string
+}
+
+// NewRedisDelayedJobStore creates store with keys prefix+"queue", prefix+"jobs" and prefix+"parked",
+// prefix should be hash tag in redis cluster, e.g. "{delayed-job:app}:"
+func NewRedisDelayedJobStore(client redis.Redis, prefix string) *RedisDelayedJobStore {
+	return &RedisDelayedJobStore{redis: scripter(client), keys: []string{prefix + "queue", prefix + "jobs", prefix + "parked"}}
+}
+
+func (s *RedisDelayedJobStore) Schedule(ctx context.Context, job *DelayedJob) error {
+	data, err := marshalDelayedJob(job)
+	if err != nil {
+		return err
+	}
+	_, err = s.redis.Eval(ctx, scheduleScript, s.keys, job.Id, job.RunAt.UnixMilli(), data)
+	return err
+}
+
+func (s *RedisDelayedJobStore) Claim(ctx context.Context, now time.Time, limit int, timeout time.Duration) ([]*DelayedJob, error) {
+	result, err := s.redis.Eval(ctx, claimScript, s.keys, now.UnixMilli(), limit, now.Add(timeout).UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	return parseDelayedJobs(result)
+}
+
+func (s *RedisDelayedJobStore) Extend(ctx context.Context, id string, until time.Time) (bool, error) {
+	extended, err := s.redis.Eval(ctx, extendScript, s.keys, id, until.UnixMilli())
+	if err != nil {
+		return false, err
+	}
+	return extended == int64(1), nil
+}
+
+func (s *RedisDelayedJobStore) Park(ctx context.Context, job *DelayedJob) error {
+	data, err := marshalDelayedJob(job)
+	if err != nil {
+		return err
+	}
+	_, err = s.redis.Eval(ctx, parkScript, s.keys, job.Id, job.RunAt.UnixMilli(), data)
+	return err
+}
+
+func (s *RedisDelayedJobStore) Complete(ctx context.Context, id string) error {
+	_, err := s.redis.Eval(ctx, removeScript, s.keys, id)
+	return err
+}
+
+// List returns up to limit jobs in queue ordered by run at, followed by parked jobs
+func (s *RedisDelayedJobStore) List(ctx context.Context, limit int) ([]*DelayedJob, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be greater than 0, limit=%d", limit)
+	}
+	result, err := s.redis.Eval(ctx, listScript, s.keys[:2], limit-1)
+	if err != nil {
+		return nil, err
+	}
+	jobs, err := parseDelayedJobs(result)
+	if err != nil || len(jobs) >= limit {
+		return jobs, err
+	}
+	result, err = s.redis.Eval(ctx, listScript, []string{s.keys[2], s.keys[1]}, limit-len(jobs)-1)
+	if err != nil {
+		return nil, err
+	}
+	parked, err := parseDelayedJobs(result)
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range parked {
+		job.Parked = true
+	}
+	return append(jobs, parked...), nil
+}
+
+func (s *RedisDelayedJobStore) Cancel(ctx context.Context, id string) (bool, error) {
+	removed, err := s.redis.Eval(ctx, removeScript, s.keys, id)
+	if err != nil {
+		return false, err
+	}
+	return removed == int64(1), nil
+}
+
+func (s *RedisDelayedJobStore) Reschedule(ctx context.Context, id string, runAt time.Time) (bool, error) {
+	updated, err := s.redis.Eval(ctx, rescheduleScript, s.keys, id, runAt.UnixMilli())
+	if err != nil {
+		return false, err
+	}
+	return updated == int64(1), nil
+}
+
+func marshalDelayedJob(job *DelayedJob) (string, error) {
+	data, err := json.Marshal(delayedJobData{Id: job.Id, Handler: job.Handler, Payload: job.Payload, RefId: job.RefId, CreatedAt: job.CreatedAt.UnixMilli(), Attempts: job.Attempts})
+	return string(data), err
+}
+
+// parseDelayedJobs parses pairs of job data and score
+func parseDelayedJobs(result any) ([]*DelayedJob, error) {
+	values, ok := result.([]any)
+	if !ok || len(values)%2 != 0 {
+		return nil, fmt.Errorf("unexpected delayed jobs result, result=%v", result)
+	}
+	jobs := make([]*DelayedJob, 0, len(values)/2)
+	for i := 0; i < len(values); i += 2 {
+		var data delayedJobData
+		if err := json.Unmarshal([]byte(fmt.Sprint(values[i])), &data); err != nil {
+			return nil, fmt.Errorf("failed to parse delayed job, data=%v: %w", values[i], err)
+		}
+		score, err := strconv.ParseFloat(fmt.Sprint(values[i+1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse delayed job score, id=%s: %w", data.Id, err)
+		}
+		jobs = append(jobs, &DelayedJob{
+			Id:        data.Id,
+			Handler:   data.Handler,
+			Payload:   data.Payload,
+			RunAt:     time.UnixMilli(int64(score)),
+			RefId:     data.RefId,
+			CreatedAt: time.UnixMilli(data.CreatedAt),
+			Attempts:  data.Attempts,
+		})
+	}
+	return jobs, nil
+}
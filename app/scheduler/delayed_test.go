@@ -0,0 +1,32 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseDelayedJobs(t *testing.T) {
+	runAt := time.UnixMilli(1760000000000)
+	jobs, err := parseDelayedJobs([]any{`{"id":"1","handler":"payout","payload":"{\"amount\":1}","ref_id":"ref","created_at":1750000000000,"attempts":2}`, "1760000000000"})
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, &DelayedJob{Id: "1", Handler: "payout", Payload: `{"amount":1}`, RunAt: runAt, RefId: "ref", CreatedAt: time.UnixMilli(1750000000000), Attempts: 2}, jobs[0])
+
+	jobs, err = parseDelayedJobs([]any{})
+	assert.NoError(t, err)
+	assert.Empty(t, jobs)
+
+	_, err = parseDelayedJobs([]any{"{}"})
+	assert.Error(t, err)
+	_, err = parseDelayedJobs([]any{"{}", "not-number"})
+	assert.Error(t, err)
+}
+
+func TestListDelayedJobsValidatesLimit(t *testing.T) {
+	store := &RedisDelayedJobStore{}
+	_, err := store.List(context.Background(), 0)
+	assert.ErrorContains(t, err, "limit must be greater than 0")
+}
@@ -11,6 +11,7 @@ type Scheduler interface {
 	AddFuncWithName(spec, action string, process func(ctx context.Context), panicOnAddError ...bool) (JobID, error)
 	Add(spec string, job Job, panicOnAddError ...bool) (JobID, error)
 	AddWithName(spec, action string, job Job, panicOnAddError ...bool) (JobID, error)
+	AddWithOptions(spec, action string, job Job, options Options) (JobID, error)
 	Remove(id JobID)
 	Start()
 	JobsInfo() []JobInfo
@@ -24,9 +25,25 @@ type Job interface {
 type JobID int
 
 type JobInfo struct {
-	ID      JobID
-	Name    string
-	Trigger string
-	Next    time.Time
-	Prev    time.Time
+	ID                  JobID
+	Name                string
+	Trigger             string
+	Next                time.Time
+	Prev                time.Time
+	NextFireTimes       []time.Time // upcoming fire times, excluded fire times of calendar are skipped
+	TimeZone            string
+	Leader              string      // owner of scheduler lease in cluster mode
+	Disabled            bool        // disabled job is not fired by trigger, but can be triggered manually
+	ConsecutiveFailures int         // reset on success
+	History             []Execution // recent executions, latest first
+}
+
+type Execution struct {
+	Start     time.Time
+	End       time.Time
+	Duration  time.Duration
+	Result    string // ok or error
+	ErrorCode string
+	ActionId  string // id of action log
+	RefId     string // id of action which triggered job manually
 }
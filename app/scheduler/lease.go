@@ -0,0 +1,150 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/odycenter/std-library/app/redis"
+	"log"
+	"strings"
+	"time"
+)
+
+// Lease elects leader of cluster, only leader fires jobs
+type Lease interface {
+	// Acquire acquires lease if it's free or expired, or renews it if owner is holder, returns current holder
+	Acquire(ctx context.Context, owner string, ttl time.Duration) (holder string, err error)
+	// Release frees lease if owner is holder, so other node takes over without waiting for expiration
+	Release(ctx context.Context, owner string) error
+}
+
+const (
+	acquireScript = `local holder = redis.call('GET', KEYS[1])
+if holder == false then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return ARGV[1]
+end
+if holder == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return holder`
+	releaseScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`
+)
+
+type RedisLease struct {
+	redis redis.Scripter
+	key   string
+}
+
+func NewRedisLease(client redis.Redis, key string) *RedisLease {
+	return &RedisLease{redis: scripter(client), key: key}
+}
+
+// scripter returns client as redis.Scripter, client of RedisConfig supports lua script
+func scripter(client redis.Redis) redis.Scripter {
+	scripter, ok := client.(redis.Scripter)
+	if !ok {
+		log.Panicf("redis client does not support Eval, type=%T", client)
+	}
+	return scripter
+}
+
+func (l *RedisLease) Acquire(ctx context.Context, owner string, ttl time.Duration) (string, error) {
+	holder, err := l.redis.Eval(ctx, acquireScript, []string{l.key}, owner, ttl.Milliseconds())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(holder), nil
+}
+
+func (l *RedisLease) Release(ctx context.Context, owner string) error {
+	_, err := l.redis.Eval(ctx, releaseScript, []string{l.key}, owner)
+	return err
+}
+
+// LeaseDDL creates table for DBLease, it works for both mysql and postgres
+func LeaseDDL(table string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+    name VARCHAR(255) NOT NULL PRIMARY KEY,
+    holder VARCHAR(255) NOT NULL,
+    expired_at BIGINT NOT NULL
+);
+`, table)
+}
+
+// DBLease stores lease as row of table in db registered by orm alias
+type DBLease struct {
+	alias string
+	table string
+	name  string
+}
+
+func NewDBLease(alias, table, name string) *DBLease {
+	return &DBLease{alias: alias, table: table, name: name}
+}
+
+// Acquire compares expiration with db time, so clock skew of nodes does not matter
+func (l *DBLease) Acquire(ctx context.Context, owner string, ttl time.Duration) (string, error) {
+	var holder string
+	err := withContext(ctx, func() error {
+		var err error
+		holder, err = l.acquire(ctx, owner, ttl)
+		return err
+	})
+	return holder, err
+}
+
+func (l *DBLease) acquire(ctx context.Context, owner string, ttl time.Duration) (string, error) {
+	db := orm.NewOrmUsingDB(l.alias)
+	now := dbNowMillis(db)
+	_, err := db.RawWithCtx(ctx, "UPDATE "+l.table+" SET holder = ?, expired_at = "+now+" + ? WHERE name = ? AND (holder = ? OR expired_at < "+now+")",
+		owner, ttl.Milliseconds(), l.name, owner).Exec()
+	if err != nil {
+		return "", err
+	}
+	_, err = db.RawWithCtx(ctx, "INSERT INTO "+l.table+" (name, holder, expired_at) VALUES (?, ?, "+now+" + ?)", l.name, owner, ttl.Milliseconds()).Exec()
+	if err != nil && !duplicate(err) { // row exists, updated above or held by other node
+		return "", err
+	}
+	var holder string
+	err = db.RawWithCtx(ctx, "SELECT holder FROM "+l.table+" WHERE name = ?", l.name).QueryRow(&holder)
+	return holder, err
+}
+
+func (l *DBLease) Release(ctx context.Context, owner string) error {
+	return withContext(ctx, func() error {
+		_, err := orm.NewOrmUsingDB(l.alias).RawWithCtx(ctx, "UPDATE "+l.table+" SET expired_at = 0 WHERE name = ? AND holder = ?", l.name, owner).Exec()
+		return err
+	})
+}
+
+// withContext returns ctx.Err() once ctx is done, orm ignores ctx of raw query, so slow db can't block caller after deadline,
+// the query still runs in background until db returns
+func withContext(ctx context.Context, query func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- query()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// dbNowMillis returns sql expression of current unix millis of db
+func dbNowMillis(db orm.Ormer) string {
+	if db.Driver().Type() == orm.DRPostgres {
+		return "CAST(EXTRACT(EPOCH FROM NOW()) * 1000 AS BIGINT)"
+	}
+	return "CAST(UNIX_TIMESTAMP(NOW(3)) * 1000 AS SIGNED)"
+}
+
+func duplicate(err error) bool {
+	message := err.Error()
+	return strings.Contains(message, "Duplicate entry") || strings.Contains(message, "duplicate key")
+}
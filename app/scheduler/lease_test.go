@@ -0,0 +1,49 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDBLeaseUsesDBTime(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	mock.ExpectQuery("SELECT current_setting").WillReturnRows(sqlmock.NewRows([]string{"timezone"}).AddRow("UTC"))
+	alias := "lease-" + strconv.FormatInt(time.Now().UnixNano(), 10) // orm aliases can't be unregistered
+	assert.NoError(t, orm.AddAliasWthDB(alias, "postgres", db))
+
+	now := "CAST(EXTRACT(EPOCH FROM NOW()) * 1000 AS BIGINT)"
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE scheduler_lease SET holder = $1, expired_at = "+now+" + $2 WHERE name = $3 AND (holder = $4 OR expired_at < "+now+")")).
+		WithArgs("node-1", int64(5000), "scheduler", "node-1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO scheduler_lease (name, holder, expired_at) VALUES ($1, $2, "+now+" + $3)")).
+		WithArgs("scheduler", "node-1", int64(5000)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT holder FROM scheduler_lease WHERE name = $1")).
+		WithArgs("scheduler").WillReturnRows(sqlmock.NewRows([]string{"holder"}).AddRow("node-1"))
+
+	holder, err := NewDBLease(alias, "scheduler_lease", "scheduler").Acquire(context.Background(), "node-1", 5*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "node-1", holder)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDBLeaseAcquireTimeout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	mock.ExpectQuery("SELECT current_setting").WillReturnRows(sqlmock.NewRows([]string{"timezone"}).AddRow("UTC"))
+	alias := "lease-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	assert.NoError(t, orm.AddAliasWthDB(alias, "postgres", db))
+	mock.ExpectExec("UPDATE scheduler_lease").WillDelayFor(time.Second).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = NewDBLease(alias, "scheduler_lease", "scheduler").Acquire(ctx, "node-1", 5*time.Second)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "hung db does not block renewal past timeout")
+}
@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"github.com/odycenter/std-library/app/redis"
+	redisV9 "github.com/redis/go-redis/v9"
+	"time"
+)
+
+// MisfirePolicy handles fire times missed during downtime or shutdown, or rejected as previous run was still running
+type MisfirePolicy int
+
+const (
+	MisfireSkip     MisfirePolicy = iota // ignore missed fire times, it's default
+	MisfireFireOnce                      // run once for all missed fire times
+	MisfireFireAll                       // run once per missed fire time
+)
+
+func (p MisfirePolicy) String() string {
+	switch p {
+	case MisfireFireOnce:
+		return "fire_once"
+	case MisfireFireAll:
+		return "fire_all"
+	default:
+		return "skip"
+	}
+}
+
+type Options struct {
+	DisallowConcurrent bool          // reject trigger if previous run is still running, rejected trigger is handled by Misfire
+	Timeout            time.Duration // max execution time, ctx passed to job is canceled after timeout
+	Retries            int           // number of retries after panic
+	RetryBackoff       time.Duration // delay before first retry, doubled each retry, 1s by default
+	Misfire            MisfirePolicy
+	TimeZone           string   // IANA time zone of spec, e.g. timex.CN, process local time zone by default
+	Calendar           Calendar // excludes fire times, e.g. holidays
+	PanicOnAddError    bool
+}
+
+// FireTimeStore persists last fire time of jobs, which is required to detect fire times missed during downtime
+type FireTimeStore interface {
+	LastFireTime(ctx context.Context, job string) (time.Time, error) // zero time if job never fired
+	Fired(ctx context.Context, job string, at time.Time) error
+}
+
+type RedisFireTimeStore struct {
+	redis  redis.Redis
+	prefix string
+}
+
+func NewRedisFireTimeStore(redis redis.Redis, prefix string) *RedisFireTimeStore {
+	return &RedisFireTimeStore{redis: redis, prefix: prefix}
+}
+
+func (s *RedisFireTimeStore) LastFireTime(ctx context.Context, job string) (time.Time, error) {
+	value, err := s.redis.Get(ctx, s.prefix+job)
+	if errors.Is(err, redisV9.Nil) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+func (s *RedisFireTimeStore) Fired(ctx context.Context, job string, at time.Time) error {
+	_, err := s.redis.Set(ctx, s.prefix+job, at.Format(time.RFC3339Nano), 0)
+	return err
+}
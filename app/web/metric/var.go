@@ -46,4 +46,113 @@ var (
 		},
 		[]string{"name"},
 	)
+	ExecutorRejected = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "executor_rejected_total",
+			Help: "The total number of tasks rejected by executor",
+		},
+		[]string{"name", "policy"},
+	)
+)
+
+var (
+	KafkaConsumerLag = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kafka_consumer_lag",
+			Help: "Number of messages behind high watermark, polled from committed offsets of group",
+		},
+		[]string{"topic", "group", "partition"},
+	)
+	KafkaMessagesConsumed = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_messages_consumed_total",
+			Help: "The total number of fetched messages",
+		},
+		[]string{"topic", "group"},
+	)
+	KafkaMessagesCommitted = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_messages_committed_total",
+			Help: "The total number of committed messages",
+		},
+		[]string{"topic", "group"},
+	)
+	KafkaMessagesFailed = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_messages_failed_total",
+			Help: "The total number of messages failed in handler",
+		},
+		[]string{"topic", "group", "error_code"},
+	)
+	KafkaHandlerDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "kafka_handler_duration_seconds",
+			Help:    "Duration of message handler, one observation per batch for bulk handler",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"topic", "group"},
+	)
+	KafkaListenerRunningTasks = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kafka_listener_running_tasks",
+			Help: "Number of running tasks in message listener",
+		},
+		[]string{"topic", "group"},
+	)
+)
+
+var (
+	OutboxPendingMessages = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "outbox_pending_messages",
+			Help: "Number of messages not published yet, updated after each relay run",
+		},
+		[]string{"name"},
+	)
+	OutboxOldestPendingSeconds = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "outbox_oldest_pending_seconds",
+			Help: "Age of the oldest message not published yet",
+		},
+		[]string{"name"},
+	)
+	OutboxPublished = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_messages_published_total",
+			Help: "The total number of messages published by relay",
+		},
+		[]string{"name", "topic"},
+	)
+	OutboxPublishFailed = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_publish_failed_total",
+			Help: "The total number of failed publish attempts",
+		},
+		[]string{"name", "topic"},
+	)
+)
+
+var (
+	JobDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "job_duration_seconds",
+			Help:    "Duration of scheduler job execution",
+			Buckets: []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 600, 1800, 3600},
+		},
+		[]string{"job", "result"},
+	)
+	JobFailed = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "job_failed_total",
+			Help: "The total number of failed scheduler job executions",
+		},
+		[]string{"job", "error_code"},
+	)
+	JobConsecutiveFailures = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "job_consecutive_failures",
+			Help: "Number of consecutive failed executions of scheduler job, reset on success",
+		},
+		[]string{"job"},
+	)
 )
@@ -0,0 +1,9 @@
+package dbase
+
+// Dialect is sql dialect of db, used to generate DDL and dialect specific statements
+type Dialect int
+
+const (
+	MySQL Dialect = iota
+	Postgres
+)
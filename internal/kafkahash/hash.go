@@ -0,0 +1,15 @@
+// Package kafkahash is key hashing shared by producers of kafka and app/kafka packages,
+// so a key goes to same partition whichever producer publishes it
+package kafkahash
+
+import "github.com/odycenter/std-library/app/log/util"
+
+// Hash returns non-negative crc32 of key
+func Hash(key []byte) uint32 {
+	return uint32(util.String(string(key)))
+}
+
+// Partition picks partition of key, key must not be empty
+func Partition(key []byte, partitions []int) int {
+	return partitions[Hash(key)%uint32(len(partitions))]
+}
@@ -0,0 +1,14 @@
+package kafkahash
+
+import (
+	"github.com/stretchr/testify/assert"
+	"hash/crc32"
+	"testing"
+)
+
+func TestPartition(t *testing.T) {
+	assert.Equal(t, crc32.ChecksumIEEE([]byte("key")), Hash([]byte("key")))
+	partitions := []int{0, 1, 2}
+	assert.Equal(t, int(Hash([]byte("key"))%3), Partition([]byte("key"), partitions))
+	assert.Equal(t, Partition([]byte("key"), partitions), Partition([]byte("key"), partitions))
+}
@@ -1,7 +1,7 @@
 package kafka
 
 import (
-	"github.com/odycenter/std-library/app/log/util"
+	"github.com/odycenter/std-library/internal/kafkahash"
 	"github.com/segmentio/kafka-go"
 	"sync"
 )
@@ -20,15 +20,7 @@ func (pf *KeyHashBalancer) BalanceByKey(keyByteArray []byte, partitions ...int)
 		return pf.rr.Balance(partitions...)
 	}
 
-	key := string(keyByteArray)
-	hashcode := util.String(key)
-	keyHash := uint32(hashcode)
-	return pf.balance(keyHash, partitions)
-}
-
-func (pf *KeyHashBalancer) balance(keyHash uint32, partitions []int) int {
-	length := uint32(len(partitions))
-	return partitions[keyHash%length]
+	return kafkahash.Partition(keyByteArray, partitions)
 }
 
 type RoundRobinBalancer struct {
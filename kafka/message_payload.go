@@ -1,6 +1,10 @@
 package kafka
 
-import "time"
+import (
+	"context"
+	appkafka "github.com/odycenter/std-library/app/kafka"
+	"time"
+)
 
 type Header struct {
 	Key   string
@@ -37,3 +41,17 @@ func NewStringMessage(topic string, value string) MessagePayload {
 		Value: []byte(value),
 	}
 }
+
+// NewEncodedMessage encodes value by codec, e.g. registry.NewAvroCodec(client, registry.ValueSubject(topic)),
+// so message published by Publish is in schema registry wire format
+func NewEncodedMessage(ctx context.Context, codec appkafka.Codec, topic string, key []byte, value any) (MessagePayload, error) {
+	data, err := codec.Encode(ctx, value)
+	if err != nil {
+		return MessagePayload{}, err
+	}
+	return MessagePayload{
+		Topic: topic,
+		Key:   key,
+		Value: data,
+	}, nil
+}
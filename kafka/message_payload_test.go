@@ -0,0 +1,44 @@
+package kafka_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/odycenter/std-library/app/kafka/registry"
+	"github.com/odycenter/std-library/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const orderSchema = `{"type":"record","name":"Order","fields":[{"name":"order_id","type":"string"}]}`
+
+type order struct {
+	OrderId string `avro:"order_id"`
+}
+
+func TestNewEncodedMessage(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/subjects/orders-value/versions/latest", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"subject": "orders-value", "version": 1, "id": 7, "schema": orderSchema})
+	})
+	mux.HandleFunc("/schemas/ids/7", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"schema": orderSchema})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	codec := registry.NewAvroCodec(registry.NewClient(server.URL), registry.ValueSubject("orders"))
+
+	msg, err := kafka.NewEncodedMessage(context.Background(), codec, "orders", []byte("o-1"), &order{OrderId: "o-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "orders", msg.Topic)
+	assert.Equal(t, []byte{0, 0, 0, 0, 7}, msg.Value[:5], "magic byte and schema id")
+
+	var decoded order
+	require.NoError(t, codec.Decode(context.Background(), msg.Value, &decoded))
+	assert.Equal(t, "o-1", decoded.OrderId)
+
+	_, err = kafka.NewEncodedMessage(context.Background(), registry.NewAvroCodec(registry.NewClient(server.URL), registry.ValueSubject("unknown")), "unknown", nil, &order{})
+	assert.Error(t, err)
+}
@@ -118,6 +118,15 @@ func CreateProducer(opt *ProducerOption) {
 			panic(err)
 		}
 	}
+	properties, err := opt.getSecurityConfig()
+	if err != nil {
+		panic(err)
+	}
+	for key, value := range properties {
+		if err := config.SetKey(key, value); err != nil {
+			panic(err)
+		}
+	}
 	p, err := kafka.NewProducer(config)
 	if err != nil {
 		panic(err)
@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	app "github.com/odycenter/std-library/app/conf"
+	appkafka "github.com/odycenter/std-library/app/kafka"
 	actionlog "github.com/odycenter/std-library/app/log"
 	"github.com/odycenter/std-library/app/log/consts/logKey"
 	"github.com/segmentio/kafka-go"
@@ -18,15 +19,16 @@ type Producer struct {
 
 // ProducerOption 生产者配置
 type ProducerOption struct {
-	AliasName         string          `json:"AliasName"`   //别名
-	BrokersAddr       []string        `json:"BrokersAddr"` //Kafka单例、集群地址
-	Balancer          *kafka.Balancer `json:"-"`           //指定平衡器模式 默认RoundRobin
-	Async             bool            `json:"Async"`       //是否异步
-	OnDelivery        OnDelivery      `json:"-"`           //同步需要完成该函数，否则线程会阻塞
-	SkipTLS           bool            `json:"SkipTLS"`     //跳过TLS验证
-	BatchSize         int             `json:"BatchSize"`   //在发送到分区之前限制请求的最大訊息量,默认使用默认值 100。
-	BatchTimeout      time.Duration   `json:"-"`           //将不完整的消息批次刷新到 kafka 的时间限制,默认至少每秒刷新一次。
-	EnableCompression bool            `json:"-"`
+	AliasName         string             `json:"AliasName"`   //别名
+	BrokersAddr       []string           `json:"BrokersAddr"` //Kafka单例、集群地址
+	Balancer          *kafka.Balancer    `json:"-"`           //指定平衡器模式 默认RoundRobin
+	Async             bool               `json:"Async"`       //是否异步
+	OnDelivery        OnDelivery         `json:"-"`           //同步需要完成该函数，否则线程会阻塞
+	SkipTLS           bool               `json:"SkipTLS"`     //跳过TLS验证
+	BatchSize         int                `json:"BatchSize"`   //在发送到分区之前限制请求的最大訊息量,默认使用默认值 100。
+	BatchTimeout      time.Duration      `json:"-"`           //将不完整的消息批次刷新到 kafka 的时间限制,默认至少每秒刷新一次。
+	EnableCompression bool               `json:"-"`
+	Security          *appkafka.Security `json:"-"` //SASL/TLS配置，同时用于CreateTopic
 }
 
 func (opt *ProducerOption) getAliasName() string {
@@ -37,19 +39,33 @@ func (opt *ProducerOption) getAliasName() string {
 }
 
 func (opt *ProducerOption) getTransport() kafka.RoundTripper {
+	if opt.Security != nil {
+		return opt.Security.Transport()
+	}
 	if opt.SkipTLS {
 		return &kafka.Transport{
 			Dial: (&net.Dialer{
 				Timeout: 3 * time.Second,
 			}).DialContext,
 			TLS: &tls.Config{
-				InsecureSkipVerify: false,
+				InsecureSkipVerify: true,
 			},
 		}
 	}
 	return kafka.DefaultTransport
 }
 
+// getSecurityConfig 返回confluent producer的安全配置，admin client(CreateTopic)由producer创建，使用相同配置
+func (opt *ProducerOption) getSecurityConfig() (map[string]string, error) {
+	if opt.Security != nil {
+		return opt.Security.LibrdkafkaConfig()
+	}
+	if opt.SkipTLS {
+		return map[string]string{"security.protocol": "SSL", "enable.ssl.certificate.verification": "false"}, nil
+	}
+	return map[string]string{}, nil
+}
+
 // WithOnCompletion 设置生产者回调处理
 func (opt *ProducerOption) WithOnCompletion(fn OnDelivery) *ProducerOption {
 	opt.OnDelivery = fn
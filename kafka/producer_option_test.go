@@ -0,0 +1,29 @@
+package kafka
+
+import (
+	appkafka "github.com/odycenter/std-library/app/kafka"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSkipTLS(t *testing.T) {
+	transport := (&ProducerOption{SkipTLS: true}).getTransport().(*kafka.Transport)
+	assert.True(t, transport.TLS.InsecureSkipVerify)
+	assert.Equal(t, kafka.DefaultTransport, (&ProducerOption{}).getTransport())
+
+	properties, err := (&ProducerOption{SkipTLS: true}).getSecurityConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "false", properties["enable.ssl.certificate.verification"])
+}
+
+func TestProducerSecurity(t *testing.T) {
+	opt := &ProducerOption{Security: &appkafka.Security{Mechanism: appkafka.MechanismPlain, Username: "user", Password: "password"}}
+	transport := opt.getTransport().(*kafka.Transport)
+	assert.Equal(t, appkafka.MechanismPlain, transport.SASL.Name())
+
+	properties, err := opt.getSecurityConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "SASL_PLAINTEXT", properties["security.protocol"])
+	assert.Equal(t, "user", properties["sasl.username"])
+}